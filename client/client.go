@@ -2,11 +2,9 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/hex"
 	"flag"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -40,28 +38,51 @@ func main() {
 
 	// search
 	metadataObject := fmt.Sprintf("{\"metadata\": \"sims_%s\"}", metadata)
-	str := base64.StdEncoding.EncodeToString([]byte(metadataObject))
-	cs, err := services.NewCouchbaseService("couchbase://localhost", "Administrator", "password", "blocks", "transactions")
+	results, err := searchPayload([]byte(metadataObject))
 	if err != nil {
-		logger.Error().Msgf("failed to create Couchbase service: %v", err)
+		logger.Error().Msgf("failed to search payload: %v", err)
+		return
 	}
-	transaction := ""
-	transaction, err = cs.SearchTransactionByPayload([]byte(str))
-	if err != nil {
-		logger.Error().Msgf("failed to search transaction: %v", err)
+	if len(results) == 0 {
+		logger.Error().Msg("matadata not found")
+		return
+	}
+	for _, res := range results {
+		logger.Info().Msgf("found in block [%d] [%s] at index [%d]", res.BlockHeight, hex.EncodeToString(res.BlockHash), res.TxIndex)
+		if searchBlockAndValidate(res.BlockHeight, res.BlockHash, metadataObject) {
+			logger.Info().Msg("matadata validated")
+		} else {
+			logger.Error().Msg("matadata not found")
+		}
 	}
-	util.Logger.Info().Msg(transaction)
-	block := ""
-	block, err = cs.SearchBlockByPayload([]byte(str))
+}
+
+// searchPayload returns every included transaction carrying the payload, the
+// node keeps the payload index.
+func searchPayload(payload []byte) ([]*proto.TxSearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := grpc.DialContext(ctx, ":3000", grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		logger.Error().Msgf("failed to search block: %v", err)
+		return nil, err
 	}
-	util.Logger.Info().Msg(block)
-	cs.Close()
-	if searchBlockAndValidate(strings.TrimPrefix(block, "block::"), metadataObject) {
-		logger.Info().Msg("matadata validated")
-	} else {
-		logger.Error().Msg("matadata not found")
+	defer client.Close()
+
+	var (
+		c       = proto.NewNodeClient(client)
+		results []*proto.TxSearchResult
+		token   []byte
+	)
+	for {
+		page, err := c.SearchByPayload(ctx, &proto.PayloadSearch{Payload: payload, PageToken: token})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, page.Transactions...)
+		if len(page.NextPageToken) == 0 {
+			return results, nil
+		}
+		token = page.NextPageToken
 	}
 }
 
@@ -90,25 +111,29 @@ func searchTransaction(index int32) []*proto.Transaction {
 	return block.Block.Transactions
 }
 
-func searchBlockAndValidate(key string, metadata string) bool {
-	db_dir := util.LoadConfig().BADGER.DataDir
-	DB, err := services.ConnectBadgerDBReadOnly(db_dir)
-	if err != nil {
-		logger.Error().Msgf("failed to create Couchbase service: %v", err)
-	}
-	length, err := DB.Len([]byte("blockStore"))
+// searchBlockAndValidate reads a block from the node and checks it is still
+// the block of its height and carries the metadata.
+func searchBlockAndValidate(height int32, hash []byte, metadata string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := grpc.DialContext(ctx, ":3000", grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		logger.Error().Msgf("failed to open Couchbase bucket: %v", err)
+		logger.Error().Msgf("did not connect to %s: %v", ":3000", err)
+		return false
 	}
-	util.Logger.Debug().Msgf("length of blockStore namespace: %d", length)
-	block, err := DB.Get([]byte("blockStore"), []byte(key))
+	defer client.Close()
+
+	res, err := proto.NewNodeClient(client).GetBlock(ctx, &proto.BlockSearch{BlockHeight: height})
 	if err != nil {
 		logger.Error().Msgf("failed to validate metadata: %v", err)
+		return false
+	}
+	if string(types.HashBlock(res.Block)) != string(hash) {
+		logger.Error().Msgf("block [%s] is no longer part of the chain", hex.EncodeToString(hash))
+		return false
 	}
-	res := services.ValidatePayload(block, metadata)
-	DB.Close()
 
-	return res
+	return services.ValidatePayload(types.BlockBytes(res.Block), metadata)
 }
 
 func sendTransaction(i int, port string, v string) {
@@ -3,13 +3,38 @@ package config
 // Config struct to hold configuration data
 type ConfigFile struct {
 	NETWORK struct {
-		Tick int `mapstructure:"tick"`
+		Tick int    `mapstructure:"tick"`
+		Sync string `mapstructure:"sync"` // blocks or headers
 	} `mapstructure:"network"`
 	KEYS struct {
 		GodSeed     string `mapstructure:"god_seed"`
 		NodePrivKey string `mapstructure:"node_priv_key"`
 	} `mapstructure:"keys"`
 	BADGER struct {
-		DataDir string `mapstructure:"data_dir"`
+		DataDir       string   `mapstructure:"data_dir"`
+		PayloadFields []string `mapstructure:"payload_fields"` // top-level fields of JSON payloads indexed for search
 	} `mapstructure:"badger"`
+	CONSENSUS struct {
+		Engine        string   `mapstructure:"engine"`        // pbft, raft, poa or solo
+		Validators    []string `mapstructure:"validators"`    // hex encoded ed25519 public keys
+		WAL           string   `mapstructure:"wal"`           // path of the consensus write-ahead log
+		RaftWAL       string   `mapstructure:"raft_wal"`      // path of the raft log, kept apart from the pbft one
+		Confirmations int      `mapstructure:"confirmations"` // blocks on top of a block that make it final, for engines without commit certificates
+	} `mapstructure:"consensus"`
+	SINKS struct {
+		COUCHBASE struct {
+			Enabled     bool   `mapstructure:"enabled"`
+			Connection  string `mapstructure:"connection"`
+			Username    string `mapstructure:"username"`
+			Password    string `mapstructure:"password"`
+			BlockBucket string `mapstructure:"block_bucket"`
+			TxBucket    string `mapstructure:"tx_bucket"`
+		} `mapstructure:"couchbase"`
+		FILE struct {
+			Enabled       bool   `mapstructure:"enabled"`
+			Dir           string `mapstructure:"dir"`
+			SegmentBlocks int    `mapstructure:"segment_blocks"`
+			SegmentBytes  int64  `mapstructure:"segment_bytes"` // uncompressed
+		} `mapstructure:"file"` // JSON Lines export for offline loads
+	} `mapstructure:"sinks"` // secondary stores fed with the committed blocks
 }
@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"bytes"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+)
+
+const (
+	// checkpointInterval is the number of sequences between two checkpoints.
+	checkpointInterval = 10
+	// watermarkWindow bounds how far above the stable checkpoint messages are accepted.
+	watermarkWindow = 100
+)
+
+// inWindow reports whether a sequence lies between the low and high watermark.
+// The high watermark only applies once a stable checkpoint exists, a node
+// restarted on top of a long chain must not be locked out before its first one.
+func (p *PBFTPoA) inWindow(sequence int) bool {
+	if sequence <= p.stableCheckpoint {
+		return false
+	}
+	return p.stableCheckpoint == 0 || sequence <= p.stableCheckpoint+p.window
+}
+
+// sendCheckpoint announces the state digest of a committed sequence. Only the
+// signed header is sent, the block hash covers the header alone.
+func (p *PBFTPoA) sendCheckpoint(b *proto.Block) {
+	p.broadcast(ConsensusMessage{
+		Type:     "Checkpoint",
+		Block:    types.SignedHeader(b),
+		View:     p.currentView,
+		Sequence: blockSequence(b),
+		NodeID:   p.nodeID,
+	})
+}
+
+func (p *PBFTPoA) handleCheckpoint(msg ConsensusMessage) {
+	if msg.Block == nil || msg.Sequence <= p.stableCheckpoint || blockSequence(msg.Block) != msg.Sequence {
+		return
+	}
+	if p.checkpoints[msg.Sequence] == nil {
+		p.checkpoints[msg.Sequence] = make(map[string]ConsensusMessage)
+	}
+	p.checkpoints[msg.Sequence][msg.NodeID] = msg
+
+	// the checkpoint becomes stable once a quorum agrees on the same digest
+	hash := types.HashBlock(msg.Block)
+	matching := 0
+	for _, cp := range p.checkpoints[msg.Sequence] {
+		if bytes.Equal(types.HashBlock(cp.Block), hash) {
+			matching++
+		}
+	}
+	if matching >= p.quorumSize {
+		p.stabilize(msg.Sequence)
+	}
+}
+
+// stabilize moves the low watermark to a stable checkpoint and drops every
+// message at or below it.
+func (p *PBFTPoA) stabilize(sequence int) {
+	p.stableCheckpoint = sequence
+	p.pruneSlots(func(s slot) bool { return s.sequence <= sequence })
+	for seq := range p.checkpoints {
+		if seq <= sequence {
+			delete(p.checkpoints, seq)
+		}
+	}
+	util.Logger.Info().Msgf("stable checkpoint at sequence [%d]", sequence)
+
+	if p.wal == nil {
+		return
+	}
+	entries, err := p.wal.Replay()
+	if err != nil {
+		util.Logger.Error().Msgf("failed to read consensus wal at sequence [%d]: [%s]", sequence, err)
+		return
+	}
+
+	// the rewritten log holds the checkpoint, the validator set, which is not
+	// part of the checkpoint, and the entries it does not cover. View changes
+	// carry no sequence, the ones of the current view and above are kept. The
+	// log is replaced at once, a crash leaves the old or the new one.
+	records := []ConsensusMessage{
+		{Type: walStable, View: p.currentView, Sequence: sequence},
+		{Type: walValidators, Sequence: sequence + 1, ValidatorSet: p.validatorSet},
+	}
+	for _, msg := range entries {
+		switch {
+		case msg.Type == "ViewChange" || msg.Type == "NewView":
+			if msg.View >= p.currentView {
+				records = append(records, msg)
+			}
+		case msg.Sequence > sequence:
+			records = append(records, msg)
+		}
+	}
+	if err := p.wal.Rewrite(records); err != nil {
+		util.Logger.Error().Msgf("failed to compact consensus wal at sequence [%d]: [%s]", sequence, err)
+	}
+}
@@ -1,6 +1,26 @@
 package consensus
 
-import "github.com/janrockdev/darkblock/proto"
+import (
+	"errors"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+)
+
+var (
+	// ErrNotProposer is returned when a node proposes a block it is not allowed to propose right now.
+	ErrNotProposer = errors.New("node is not the proposer")
+	// ErrInvalidBlock is returned for a proposed block that fails validation.
+	ErrInvalidBlock = errors.New("invalid block")
+	// ErrUnknownParent is returned by a BlockValidator for a block whose parent
+	// the node has not applied yet.
+	ErrUnknownParent = errors.New("parent block is not known")
+)
+
+// BlockValidator checks a proposed block against the chain rules of the node
+// before the engine votes for it.
+type BlockValidator func(b *proto.Block) error
 
 type Consensus interface {
 	Start()
@@ -8,6 +28,20 @@ type Consensus interface {
 	ProposeBlock(b *proto.Block) error
 	ValidateBlock(b *proto.Block) bool
 	OnReceiveMessage(msg ConsensusMessage)
+	Committed() <-chan Commit
+}
+
+// Commit is a block finalized by the consensus engine. Only committed blocks
+// are appended to the chain.
+type Commit struct {
+	Block       *proto.Block
+	View        int
+	Certificate *proto.CommitCertificate // nil for engines without commit votes
+}
+
+// Transport delivers consensus messages to the other nodes of the network.
+type Transport interface {
+	BroadcastConsensusMessage(msg ConsensusMessage) error
 }
 
 type ConsensusMessage struct {
@@ -16,4 +50,73 @@ type ConsensusMessage struct {
 	Signature []byte
 	NodeID    string
 	View      int
+	Sequence  int                // height of the block the message refers to
+	Proof     []ConsensusMessage // prepared certificate or view changes backing the message
+
+	ValidatorSet *proto.ValidatorSet // only in write-ahead log records
+}
+
+// Hash returns the canonical hash of the message that validators sign.
+func (msg ConsensusMessage) Hash() []byte {
+	var blockHash []byte
+	if msg.Block != nil {
+		blockHash = types.HashBlock(msg.Block)
+	}
+	return types.HashConsensusMessage(msg.Type, int64(msg.View), int64(msg.Sequence), blockHash)
+}
+
+// Sign signs the message with the private key of the sending validator.
+func (msg *ConsensusMessage) Sign(privKey *crypto.PrivateKey) {
+	msg.Signature = privKey.Sign(msg.Hash()).Bytes()
+}
+
+// Verify checks the message signature against the public key of the claimed validator.
+func (msg ConsensusMessage) Verify(pubKey *crypto.PublicKey) bool {
+	if pubKey == nil || len(msg.Signature) != crypto.SignatureLen {
+		return false
+	}
+	return crypto.SignatureFromBytes(msg.Signature).Verify(pubKey, msg.Hash())
+}
+
+// blockSequence returns the sequence number of a block, its height.
+func blockSequence(b *proto.Block) int {
+	return int(b.GetHeader().GetHeight())
+}
+
+// ToProto converts a consensus message into its wire representation.
+func (msg ConsensusMessage) ToProto() *proto.ConsensusMessage {
+	proof := make([]*proto.ConsensusMessage, len(msg.Proof))
+	for i, m := range msg.Proof {
+		proof[i] = m.ToProto()
+	}
+	return &proto.ConsensusMessage{
+		Type:      msg.Type,
+		Block:     msg.Block,
+		Signature: msg.Signature,
+		NodeID:    msg.NodeID,
+		View:      int64(msg.View),
+		Sequence:  int64(msg.Sequence),
+		Proof:     proof,
+
+		ValidatorSet: msg.ValidatorSet,
+	}
+}
+
+// MessageFromProto converts a wire consensus message into a ConsensusMessage.
+func MessageFromProto(msg *proto.ConsensusMessage) ConsensusMessage {
+	var proof []ConsensusMessage
+	for _, m := range msg.GetProof() {
+		proof = append(proof, MessageFromProto(m))
+	}
+	return ConsensusMessage{
+		Type:      msg.GetType(),
+		Block:     msg.GetBlock(),
+		Signature: msg.GetSignature(),
+		NodeID:    msg.GetNodeID(),
+		View:      int(msg.GetView()),
+		Sequence:  int(msg.GetSequence()),
+		Proof:     proof,
+
+		ValidatorSet: msg.GetValidatorSet(),
+	}
 }
@@ -0,0 +1,48 @@
+package consensus
+
+import (
+	"encoding/hex"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+)
+
+// EvidenceReporter is implemented by engines that detect equivocating validators.
+type EvidenceReporter interface {
+	Evidence() <-chan *proto.Evidence
+}
+
+// Evidence returns the channel on which proofs of equivocation are delivered,
+// the node gossips them and includes them in a future block.
+func (p *PBFTPoA) Evidence() <-chan *proto.Evidence {
+	return p.evidence
+}
+
+// checkConflictingVote reports a validator whose vote conflicts with the one it
+// already cast in the same slot.
+func (p *PBFTPoA) checkConflictingVote(prev, msg ConsensusMessage) {
+	if prev.Block == nil || msg.Block == nil {
+		return
+	}
+	p.reportEvidence(types.NewVoteEvidence(p.known[msg.NodeID], prev.ToProto(), msg.ToProto()))
+}
+
+// reportEvidence hands evidence to the node once per validator.
+func (p *PBFTPoA) reportEvidence(ev *proto.Evidence) {
+	if ev == nil {
+		return
+	}
+	accused := hex.EncodeToString(ev.PublicKey)
+	if p.accused[accused] {
+		return
+	}
+	p.accused[accused] = true
+
+	util.Logger.Warn().Msgf("validator [%s] equivocated", accused)
+	select {
+	case p.evidence <- ev:
+	default:
+		util.Logger.Error().Msgf("evidence queue full, dropping evidence against [%s]", accused)
+	}
+}
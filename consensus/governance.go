@@ -0,0 +1,98 @@
+package consensus
+
+import (
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// walValidators records the validator set after a committed block changed it.
+const walValidators = "Validators"
+
+// SetValidatorSet starts the engine from the on-chain validator set instead of
+// the configured validators. It must be called before Start.
+func (p *PBFTPoA) SetValidatorSet(set *proto.ValidatorSet) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(set.GetValidators()) > 0 {
+		p.useValidatorSet(set)
+	}
+}
+
+// applyValidatorSet moves the validator set past a committed block. Every
+// validator commits the same blocks, so the set changes everywhere at the same
+// sequence.
+func (p *PBFTPoA) applyValidatorSet(b *proto.Block) {
+	next := types.NextValidatorSet(p.validatorSet, b)
+
+	height := next.Height
+	next.Height = p.validatorSet.Height
+	changed := !pb.Equal(next, p.validatorSet)
+	next.Height = height
+	if !changed {
+		p.validatorSet = next
+		return
+	}
+
+	if err := p.logEntry(ConsensusMessage{Type: walValidators, Sequence: blockSequence(b), ValidatorSet: next}); err != nil {
+		util.Logger.Error().Msgf("failed to log validator set of sequence [%d]: [%s]", blockSequence(b), err)
+	}
+	p.useValidatorSet(next)
+}
+
+// useValidatorSet makes a validator set the current one. The votes of removed
+// validators are dropped.
+func (p *PBFTPoA) useValidatorSet(set *proto.ValidatorSet) {
+	var (
+		ids   = make([]string, 0, len(set.Validators))
+		known = make(map[string]*crypto.PublicKey, len(set.Validators))
+	)
+	for _, v := range set.Validators {
+		if len(v) != crypto.PubKeyLen {
+			continue
+		}
+		pubKey := crypto.PublicKeyFromBytes(v)
+		ids = append(ids, pubKey.String())
+		known[pubKey.String()] = pubKey
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	for id := range p.known {
+		if known[id] == nil {
+			p.dropVotes(id)
+			util.Logger.Warn().Msgf("removed validator [%s]", id)
+		}
+	}
+	for id := range known {
+		if p.known[id] == nil {
+			util.Logger.Info().Msgf("added validator [%s]", id)
+		}
+	}
+
+	p.validatorSet = set
+	p.validators = ids
+	p.known = known
+	p.quorumSize = types.ValidatorSetQuorum(set)
+	p.isValidator = known[p.nodeID] != nil
+	util.Logger.Info().Msgf("validator set at height [%d] has [%d] validators with quorum [%d]", set.Height, len(p.validators), p.quorumSize)
+}
+
+// dropVotes deletes the votes, checkpoints and view changes of a validator.
+func (p *PBFTPoA) dropVotes(id string) {
+	for _, votes := range []map[slot]map[string]ConsensusMessage{p.prepareVotes, p.commitVotes} {
+		for _, byNode := range votes {
+			delete(byNode, id)
+		}
+	}
+	for _, byNode := range p.checkpoints {
+		delete(byNode, id)
+	}
+	for _, byNode := range p.viewChanges {
+		delete(byNode, id)
+	}
+}
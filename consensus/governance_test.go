@@ -0,0 +1,87 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGovernanceTakesEffectAtHeight(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		added   = crypto.GeneratePrivateKey().Public()
+		path    = filepath.Join(t.TempDir(), "consensus.wal")
+		g       = &proto.Governance{Action: types.GovernanceAddValidator, Validator: added.Bytes(), EffectiveHeight: 3}
+	)
+	types.ApproveGovernance(privKey, g)
+
+	wal, err := OpenFileWAL(path)
+	require.Nil(t, err)
+	engine := NewPBFTPoA(nil, privKey, nil, wal)
+	var block *proto.Block
+	commit := func(txx ...*proto.Transaction) {
+		block = nextBlock(t, privKey, block, txx...)
+		require.Nil(t, engine.handleProposal(block))
+		<-engine.Committed()
+	}
+
+	commit(&proto.Transaction{Version: 1, Governance: g})
+	assert.Len(t, engine.validators, 1)
+	assert.Len(t, engine.validatorSet.Pending, 1)
+
+	// the block before the effective height is the last one of the single validator
+	commit()
+	assert.Equal(t, []string{privKey.Public().String(), added.String()}, engine.validators)
+	assert.Equal(t, types.QuorumSize(2), engine.quorumSize)
+	assert.NotNil(t, engine.known[added.String()])
+	require.Nil(t, wal.Close())
+
+	// the validator set is restored from the log
+	wal, err = OpenFileWAL(path)
+	require.Nil(t, err)
+	defer wal.Close()
+	restarted := NewPBFTPoA(nil, privKey, nil, wal)
+	restarted.replay()
+	restarted.stopViewTimer()
+	assert.Equal(t, engine.validators, restarted.validators)
+	assert.Equal(t, int64(2), restarted.validatorSet.Height)
+}
+
+func TestUnapprovedGovernanceIsRejected(t *testing.T) {
+	var (
+		keys       = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		validators = []*crypto.PublicKey{keys[0].Public(), keys[1].Public(), keys[2].Public(), keys[3].Public()}
+		engine     = NewPBFTPoA(validators, keys[0], nil, nil)
+		g          = &proto.Governance{Action: types.GovernanceRemoveValidator, Validator: keys[3].Public().Bytes(), EffectiveHeight: 2}
+	)
+	// a leader alone cannot change the validator set
+	types.ApproveGovernance(keys[0], g)
+	assert.False(t, engine.ValidateBlock(nextBlock(t, keys[0], nil, &proto.Transaction{Version: 1, Governance: g})))
+
+	types.ApproveGovernance(keys[1], g)
+	types.ApproveGovernance(keys[2], g)
+	assert.True(t, engine.ValidateBlock(nextBlock(t, keys[0], nil, &proto.Transaction{Version: 1, Governance: g})))
+}
+
+func TestSetValidatorSet(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		other   = crypto.GeneratePrivateKey().Public()
+		engine  = NewPBFTPoA(nil, privKey, nil, nil)
+	)
+	set := types.NewValidatorSet([]*crypto.PublicKey{other, privKey.Public()})
+	set.Quorum = 2
+	engine.SetValidatorSet(set)
+	assert.Equal(t, []string{other.String(), privKey.Public().String()}, engine.validators)
+	assert.Equal(t, 2, engine.quorumSize)
+	assert.True(t, engine.isValidator)
+
+	// an empty set keeps the configured validators
+	engine.SetValidatorSet(&proto.ValidatorSet{})
+	assert.Len(t, engine.validators, 2)
+}
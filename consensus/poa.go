@@ -0,0 +1,177 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+)
+
+// defaultSlotDuration is used when no block time is configured.
+const defaultSlotDuration = 5 * time.Second
+
+// RoundRobinPoA is a proof of authority engine with time slots. The proposer of
+// a slot is picked round-robin from the validator set, its signed block is
+// final once received. A proposer that is down only costs its own slot.
+type RoundRobinPoA struct {
+	mu            sync.Mutex
+	validators    []*crypto.PublicKey
+	privKey       *crypto.PrivateKey
+	nodeID        string
+	slotDuration  time.Duration
+	lastSlot      int64
+	lastCommitted int
+	pending       []Commit // finalized blocks the run loop has not delivered yet
+	ready         chan struct{}
+	committed     chan Commit
+	transport     Transport
+	stopCh        chan struct{}
+}
+
+// NewRoundRobinPoA creates a round-robin engine for the given validator set, an
+// empty set makes the node the only validator.
+func NewRoundRobinPoA(validators []*crypto.PublicKey, privKey *crypto.PrivateKey, transport Transport, slotDuration time.Duration) *RoundRobinPoA {
+	if len(validators) == 0 {
+		validators = []*crypto.PublicKey{privKey.Public()}
+	}
+	if slotDuration <= 0 {
+		slotDuration = defaultSlotDuration
+	}
+	return &RoundRobinPoA{
+		validators:   validators,
+		privKey:      privKey,
+		nodeID:       privKey.Public().String(),
+		slotDuration: slotDuration,
+		lastSlot:     -1,
+		ready:        make(chan struct{}, 1),
+		committed:    make(chan Commit, 16),
+		transport:    transport,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+func (p *RoundRobinPoA) Start() {
+	util.Logger.Info().Msgf("starting round-robin PoA consensus with [%d] validators and [%s] slots", len(p.validators), p.slotDuration)
+	go p.run()
+}
+
+func (p *RoundRobinPoA) Stop() {
+	close(p.stopCh)
+}
+
+// run delivers the finalized blocks in order, a slow consumer never holds up
+// the callers of the engine.
+func (p *RoundRobinPoA) run() {
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-p.ready:
+		}
+
+		p.mu.Lock()
+		commits := p.pending
+		p.pending = nil
+		p.mu.Unlock()
+		for _, c := range commits {
+			select {
+			case p.committed <- c:
+			case <-p.stopCh:
+				return
+			}
+		}
+	}
+}
+
+// slot returns the time slot a block was built in.
+func (p *RoundRobinPoA) slot(b *proto.Block) int64 {
+	return b.GetHeader().GetTimestamp() / p.slotDuration.Nanoseconds()
+}
+
+// proposer returns the validator allowed to propose in a slot.
+func (p *RoundRobinPoA) proposer(slot int64) *crypto.PublicKey {
+	return p.validators[slot%int64(len(p.validators))]
+}
+
+// ProposeBlock finalizes and broadcasts a block when this node is the
+// proposer of the slot the block was built in.
+func (p *RoundRobinPoA) ProposeBlock(b *proto.Block) error {
+	if p.proposer(p.slot(b)).String() != p.nodeID {
+		return ErrNotProposer
+	}
+
+	msg := ConsensusMessage{
+		Type:     "Proposal",
+		Block:    b,
+		View:     int(p.slot(b)),
+		Sequence: blockSequence(b),
+		NodeID:   p.nodeID,
+	}
+	msg.Sign(p.privKey)
+	if err := p.commit(msg); err != nil {
+		return err
+	}
+
+	if p.transport != nil {
+		if err := p.transport.BroadcastConsensusMessage(msg); err != nil {
+			util.Logger.Error().Msgf("failed to broadcast proposal of slot [%d]: [%s]", msg.View, err)
+		}
+	}
+	return nil
+}
+
+func (p *RoundRobinPoA) OnReceiveMessage(msg ConsensusMessage) {
+	if msg.Type != "Proposal" || msg.Block == nil {
+		return
+	}
+	proposer := p.proposer(p.slot(msg.Block))
+	if msg.NodeID != proposer.String() || !msg.Verify(proposer) {
+		util.Logger.Warn().Msgf("dropping proposal of slot [%d] from [%s], not the proposer", msg.View, msg.NodeID)
+		return
+	}
+	// blocks from slots that have not started yet are not accepted
+	if time.Unix(0, msg.Block.Header.Timestamp).After(time.Now().Add(p.slotDuration)) {
+		util.Logger.Warn().Msgf("dropping proposal of future slot [%d] from [%s]", msg.View, msg.NodeID)
+		return
+	}
+	if err := p.commit(msg); err != nil {
+		util.Logger.Warn().Msgf("dropping proposal of slot [%d] from [%s]: [%s]", msg.View, msg.NodeID, err)
+	}
+}
+
+// commit finalizes the block of a proposal, one block per slot at increasing heights.
+func (p *RoundRobinPoA) commit(msg ConsensusMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.ValidateBlock(msg.Block) || msg.View != int(p.slot(msg.Block)) || msg.Sequence != blockSequence(msg.Block) {
+		return ErrNotProposer
+	}
+	if int64(msg.View) <= p.lastSlot || msg.Sequence <= p.lastCommitted {
+		return ErrStaleSequence
+	}
+	p.lastSlot = int64(msg.View)
+	p.lastCommitted = msg.Sequence
+
+	util.Logger.Info().Msgf("finalized block [%s] in slot [%d] sequence [%d]", hex.EncodeToString(types.HashBlock(msg.Block))[:8], msg.View, msg.Sequence)
+	p.pending = append(p.pending, Commit{Block: msg.Block, View: msg.View})
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// ValidateBlock accepts blocks signed by the proposer of their slot.
+func (p *RoundRobinPoA) ValidateBlock(b *proto.Block) bool {
+	return bytes.Equal(b.PublicKey, p.proposer(p.slot(b)).Bytes()) && types.VerifyBlock(b)
+}
+
+func (p *RoundRobinPoA) Committed() <-chan Commit {
+	return p.committed
+}
@@ -0,0 +1,118 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slotTransport delivers proposals straight to the other engine.
+type slotTransport struct {
+	peer *RoundRobinPoA
+}
+
+func (t *slotTransport) BroadcastConsensusMessage(msg ConsensusMessage) error {
+	t.peer.OnReceiveMessage(msg)
+	return nil
+}
+
+func TestRoundRobinProposers(t *testing.T) {
+	var (
+		keys       = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		validators = []*crypto.PublicKey{keys[0].Public(), keys[1].Public()}
+		slot       = time.Second
+		first      = NewRoundRobinPoA(validators, keys[0], nil, slot)
+		second     = NewRoundRobinPoA(validators, keys[1], &slotTransport{}, slot)
+	)
+	first.transport = &slotTransport{peer: second}
+	second.transport = &slotTransport{peer: first}
+	for _, engine := range []*RoundRobinPoA{first, second} {
+		engine.Start()
+		defer engine.Stop()
+	}
+
+	// a block built in slot s is proposed by validator s % 2
+	blockInSlot := func(s int64, height int32, privKey *crypto.PrivateKey) *proto.Block {
+		b := util.RandomBlock()
+		b.Header.Height = height
+		b.Header.Timestamp = s * slot.Nanoseconds()
+		types.SignBlock(privKey, b)
+		return b
+	}
+	now := time.Now().UnixNano() / slot.Nanoseconds()
+	even := now - now%2
+
+	assert.ErrorIs(t, second.ProposeBlock(blockInSlot(even, 1, keys[1])), ErrNotProposer)
+
+	block := blockInSlot(even, 1, keys[0])
+	require.Nil(t, first.ProposeBlock(block))
+	for _, engine := range []*RoundRobinPoA{first, second} {
+		c := waitCommit(t, engine)
+		assert.Equal(t, types.HashBlock(block), types.HashBlock(c.Block))
+	}
+
+	// the slot is used up, the next block comes from the other validator
+	assert.ErrorIs(t, first.ProposeBlock(blockInSlot(even, 2, keys[0])), ErrStaleSequence)
+	block = blockInSlot(even+1, 2, keys[1])
+	require.Nil(t, second.ProposeBlock(block))
+	c := waitCommit(t, first)
+	assert.Equal(t, int(even+1), c.View)
+}
+
+func TestRoundRobinRejectsForgedBlock(t *testing.T) {
+	var (
+		keys       = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		validators = []*crypto.PublicKey{keys[0].Public(), keys[1].Public()}
+		slot       = time.Second
+		engine     = NewRoundRobinPoA(validators, keys[1], nil, slot)
+	)
+	now := time.Now().UnixNano() / slot.Nanoseconds()
+	b := util.RandomBlock()
+	b.Header.Height = 1
+	b.Header.Timestamp = (now - now%2) * slot.Nanoseconds()
+	types.SignBlock(keys[0], b)
+	assert.True(t, engine.ValidateBlock(b))
+
+	// a relayed proposal carries the proposer key, but the block was changed
+	// after the proposer signed it
+	b.Header.Height = 2
+	msg := ConsensusMessage{Type: "Proposal", Block: b, View: int(engine.slot(b)), Sequence: blockSequence(b), NodeID: keys[0].Public().String()}
+	msg.Sign(keys[0])
+	assert.False(t, engine.ValidateBlock(b))
+	engine.OnReceiveMessage(msg)
+	assert.Zero(t, engine.lastCommitted)
+}
+
+func TestRoundRobinCommitDoesNotWait(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		slot    = time.Millisecond
+		engine  = NewRoundRobinPoA(nil, privKey, nil, slot)
+		now     = time.Now().UnixNano() / slot.Nanoseconds()
+		blocks  = []*proto.Block{}
+	)
+	engine.Start()
+	defer engine.Stop()
+
+	// nobody reads the commits while more blocks finalize than the channel holds
+	for i := range cap(engine.committed) + 4 {
+		b := util.RandomBlock()
+		b.Header.Height = int32(i + 1)
+		b.Header.Timestamp = (now + int64(i)) * slot.Nanoseconds()
+		types.SignBlock(privKey, b)
+		require.Nil(t, engine.ProposeBlock(b))
+		blocks = append(blocks, b)
+	}
+
+	// they are delivered in order once the consumer catches up
+	for _, b := range blocks {
+		c := waitCommit(t, engine)
+		assert.Equal(t, types.HashBlock(b), types.HashBlock(c.Block))
+	}
+}
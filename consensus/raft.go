@@ -0,0 +1,799 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	"golang.org/x/crypto/sha3"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	// raftElectionTimeout is the shortest time a follower waits for its leader,
+	// every wait is randomized up to twice as long to avoid split votes.
+	raftElectionTimeout = time.Second
+	// raftHeartbeat is how often the leader replicates, with or without new entries.
+	raftHeartbeat = 250 * time.Millisecond
+	// raftBatchSize bounds the entries or blocks sent in one message.
+	raftBatchSize = 64
+	// raftSnapshotInterval is the number of applied entries kept before the log
+	// is compacted into the block store.
+	raftSnapshotInterval = 100
+)
+
+const (
+	raftFollower = iota
+	raftCandidate
+	raftLeader
+)
+
+// WAL records of the raft engine.
+//
+//	Term     - the current term in View and the vote of the term in NodeID
+//	Entry    - a log entry, its term in View and index in Sequence
+//	Snapshot - the last compacted entry, its term in View, index in Sequence
+//	           and the height of the last block it covers in Block
+const (
+	walTerm     = "Term"
+	walEntry    = "Entry"
+	walSnapshot = "Snapshot"
+)
+
+// RaftTransport delivers raft messages. Messages name their addressee in To,
+// an empty To addresses all validators.
+type RaftTransport interface {
+	SendRaftMessage(msg *proto.RaftMessage) error
+}
+
+// RaftReceiver is implemented by engines that take raft messages.
+type RaftReceiver interface {
+	OnReceiveRaftMessage(msg *proto.RaftMessage)
+}
+
+// BlockSource gives an engine access to the blocks the node persisted.
+type BlockSource interface {
+	Height() int
+	GetBlockByHeight(height int) (*proto.Block, error)
+}
+
+type raftEntry struct {
+	term  int64
+	index int64
+	block *proto.Block // nil for the no-op entry a leader appends when elected
+}
+
+// Raft is a crash fault tolerant engine for deployments where all validators
+// are trusted. Blocks are entries of a replicated log and final once a
+// majority stores them. Applied entries are compacted into the block store of
+// the node, which serves as the snapshot for followers that fall behind.
+type Raft struct {
+	id      string
+	peers   []string // hex encoded public keys of all validators, this node included
+	known   map[string]*crypto.PublicKey
+	privKey *crypto.PrivateKey
+
+	role     int
+	term     int64
+	votedFor string
+	leaderID string
+	votes    map[string]bool
+
+	log            []raftEntry // entries after the snapshot
+	snapshotIndex  int64
+	snapshotTerm   int64
+	snapshotHeight int64
+	commitIndex    int64
+	lastApplied    int64
+	appliedHeight  int64
+
+	nextIndex  map[string]int64
+	matchIndex map[string]int64
+	peerHeight map[string]int64
+
+	electionTimeout time.Duration
+	heartbeat       time.Duration
+	electionTimer   *time.Timer
+
+	proposals chan proposal
+	incoming  chan *proto.RaftMessage
+	committed chan Commit
+	started   atomic.Bool // messages are dropped until the engine runs
+
+	transport RaftTransport
+	wal       WAL
+	blocks    BlockSource
+
+	stopCh chan struct{}
+}
+
+// NewRaft creates a raft engine for the given validator set, an empty set makes
+// the node the only validator. The log is kept in wal and compacted into blocks,
+// both may be nil for a node that keeps no state across restarts.
+func NewRaft(validators []*crypto.PublicKey, privKey *crypto.PrivateKey, transport RaftTransport, wal WAL, blocks BlockSource) *Raft {
+	electionTimer := time.NewTimer(raftElectionTimeout)
+	electionTimer.Stop()
+
+	if len(validators) == 0 {
+		validators = []*crypto.PublicKey{privKey.Public()}
+	}
+	var (
+		peers = make([]string, 0, len(validators))
+		known = make(map[string]*crypto.PublicKey, len(validators))
+	)
+	for _, v := range validators {
+		if _, ok := known[v.String()]; ok {
+			continue
+		}
+		peers = append(peers, v.String())
+		known[v.String()] = v
+	}
+
+	return &Raft{
+		id:              privKey.Public().String(),
+		peers:           peers,
+		known:           known,
+		privKey:         privKey,
+		votes:           make(map[string]bool),
+		nextIndex:       make(map[string]int64),
+		matchIndex:      make(map[string]int64),
+		peerHeight:      make(map[string]int64),
+		electionTimeout: raftElectionTimeout,
+		heartbeat:       raftHeartbeat,
+		electionTimer:   electionTimer,
+		proposals:       make(chan proposal),
+		incoming:        make(chan *proto.RaftMessage, 100),
+		committed:       make(chan Commit, 16),
+		transport:       transport,
+		wal:             wal,
+		blocks:          blocks,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+func (r *Raft) Start() {
+	util.Logger.Info().Msgf("starting raft consensus with [%d] validators", len(r.peers))
+	r.replay()
+	// blocks already in the store are never applied again
+	if r.blocks != nil {
+		r.appliedHeight = max(r.appliedHeight, int64(r.blocks.Height()))
+	}
+	r.resetElectionTimer()
+	r.started.Store(true)
+	go r.run()
+}
+
+func (r *Raft) Stop() {
+	close(r.stopCh)
+}
+
+func (r *Raft) run() {
+	heartbeat := time.NewTicker(r.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			r.electionTimer.Stop()
+			return
+		case prop := <-r.proposals:
+			prop.result <- r.handleProposal(prop.block)
+		case msg := <-r.incoming:
+			r.handleMessage(msg)
+		case <-r.electionTimer.C:
+			r.startElection()
+		case <-heartbeat.C:
+			if r.role == raftLeader {
+				r.replicateAll()
+			}
+		}
+	}
+}
+
+// ProposeBlock appends a block to the log of the leader. Other validators
+// return ErrNotProposer, the leader only takes the block that follows the
+// last one in its log once everything before it is committed.
+func (r *Raft) ProposeBlock(b *proto.Block) error {
+	result := make(chan error, 1)
+	select {
+	case r.proposals <- proposal{block: b, result: result}:
+	case <-r.stopCh:
+		return errors.New("consensus engine stopped")
+	}
+	return <-result
+}
+
+func (r *Raft) handleProposal(b *proto.Block) error {
+	if r.role != raftLeader {
+		return ErrNotProposer
+	}
+	if r.commitIndex < r.lastIndex() {
+		return ErrProposalInFlight
+	}
+	height := int64(blockSequence(b))
+	if height <= r.logHeight() {
+		return ErrStaleSequence
+	}
+	if height != r.logHeight()+1 {
+		return errors.New("block height does not follow the log")
+	}
+	if !r.ValidateBlock(b) {
+		return ErrInvalidBlock
+	}
+
+	if err := r.appendEntry(b); err != nil {
+		return err
+	}
+	util.Logger.Info().Msgf("appended block [%s] height [%d] at index [%d] term [%d]", hex.EncodeToString(types.HashBlock(b))[:3], height, r.lastIndex(), r.term)
+	r.replicateAll()
+	r.advanceCommit()
+	return nil
+}
+
+// ValidateBlock checks the proposer signature and that the block extends the
+// last block of the log, raft validators trust each other beyond that.
+func (r *Raft) ValidateBlock(b *proto.Block) bool {
+	if !types.VerifyBlock(b) {
+		return false
+	}
+	prev := r.lastBlock()
+	return prev == nil || bytes.Equal(b.Header.PrevHash, types.HashBlock(prev))
+}
+
+// lastBlock returns the last block of the log, or of the block store once the
+// log is compacted, nil when neither has it yet.
+func (r *Raft) lastBlock() *proto.Block {
+	height := r.logHeight()
+	for i := len(r.log) - 1; i >= 0; i-- {
+		if b := r.log[i].block; b != nil && int64(blockSequence(b)) == height {
+			return b
+		}
+	}
+	if r.blocks == nil || int64(r.blocks.Height()) < height {
+		return nil
+	}
+	b, err := r.blocks.GetBlockByHeight(int(height))
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// OnReceiveMessage ignores consensus messages of the BFT engines.
+func (r *Raft) OnReceiveMessage(msg ConsensusMessage) {}
+
+// OnReceiveRaftMessage queues a message for the run loop without waiting, the
+// leader sends again what a dropped message carried.
+func (r *Raft) OnReceiveRaftMessage(msg *proto.RaftMessage) {
+	if !r.started.Load() {
+		return
+	}
+	select {
+	case r.incoming <- msg:
+	default:
+		util.Logger.Warn().Msgf("dropping [%s] raft message of term [%d], the queue is full", msg.Type, msg.Term)
+	}
+}
+
+func (r *Raft) Committed() <-chan Commit {
+	return r.committed
+}
+
+func (r *Raft) majority() int {
+	return len(r.peers)/2 + 1
+}
+
+func (r *Raft) lastIndex() int64 {
+	return r.snapshotIndex + int64(len(r.log))
+}
+
+// termAt returns the term of the entry at index, -1 when the entry is unknown.
+func (r *Raft) termAt(index int64) int64 {
+	switch {
+	case index == r.snapshotIndex:
+		return r.snapshotTerm
+	case index < r.snapshotIndex || index > r.lastIndex():
+		return -1
+	}
+	return r.log[index-r.snapshotIndex-1].term
+}
+
+func (r *Raft) entry(index int64) raftEntry {
+	return r.log[index-r.snapshotIndex-1]
+}
+
+// logHeight returns the height of the last block in the log.
+func (r *Raft) logHeight() int64 {
+	for i := len(r.log) - 1; i >= 0; i-- {
+		if r.log[i].block != nil {
+			return max(int64(blockSequence(r.log[i].block)), r.appliedHeight)
+		}
+	}
+	return max(r.snapshotHeight, r.appliedHeight)
+}
+
+func (r *Raft) resetElectionTimer() {
+	if !r.electionTimer.Stop() {
+		select {
+		case <-r.electionTimer.C:
+		default:
+		}
+	}
+	r.electionTimer.Reset(r.electionTimeout + time.Duration(rand.Int63n(int64(r.electionTimeout))))
+}
+
+// startElection makes the node a candidate for the next term.
+func (r *Raft) startElection() {
+	if r.role == raftLeader {
+		return
+	}
+	r.role = raftCandidate
+	r.term++
+	r.votedFor = r.id
+	r.leaderID = ""
+	r.votes = map[string]bool{r.id: true}
+	if err := r.persistTerm(); err != nil {
+		util.Logger.Error().Msgf("failed to log term [%d], not running for leader: [%s]", r.term, err)
+		r.resetElectionTimer()
+		return
+	}
+	r.resetElectionTimer()
+
+	util.Logger.Info().Msgf("starting election for term [%d]", r.term)
+	if len(r.votes) >= r.majority() {
+		r.becomeLeader()
+		return
+	}
+	r.send(&proto.RaftMessage{
+		Type:     "RequestVote",
+		Term:     r.term,
+		LogIndex: r.lastIndex(),
+		LogTerm:  r.termAt(r.lastIndex()),
+	})
+}
+
+func (r *Raft) becomeLeader() {
+	r.role = raftLeader
+	r.leaderID = r.id
+	for _, peer := range r.peers {
+		r.nextIndex[peer] = r.lastIndex() + 1
+		r.matchIndex[peer] = 0
+	}
+	util.Logger.Info().Msgf("elected leader for term [%d]", r.term)
+
+	// entries of earlier terms only commit together with one of the new term
+	if err := r.appendEntry(nil); err != nil {
+		util.Logger.Error().Msgf("failed to append no-op entry: [%s]", err)
+	}
+	r.replicateAll()
+	r.advanceCommit()
+}
+
+// stepDown follows a newer term.
+func (r *Raft) stepDown(term int64) {
+	r.role = raftFollower
+	r.term = term
+	r.votedFor = ""
+	if err := r.persistTerm(); err != nil {
+		util.Logger.Error().Msgf("failed to log term [%d]: [%s]", term, err)
+	}
+	r.resetElectionTimer()
+}
+
+func (r *Raft) appendEntry(b *proto.Block) error {
+	e := raftEntry{term: r.term, index: r.lastIndex() + 1, block: b}
+	if err := r.persistEntry(e); err != nil {
+		return err
+	}
+	r.log = append(r.log, e)
+	return nil
+}
+
+func (r *Raft) handleMessage(msg *proto.RaftMessage) {
+	if msg.To != "" && msg.To != r.id {
+		return
+	}
+	if msg.From == r.id || !r.verify(msg) {
+		util.Logger.Warn().Msgf("dropping [%s] raft message from [%s]", msg.Type, msg.From)
+		return
+	}
+	if msg.Term > r.term {
+		r.stepDown(msg.Term)
+	}
+
+	switch msg.Type {
+	case "RequestVote":
+		r.handleRequestVote(msg)
+	case "Vote":
+		r.handleVote(msg)
+	case "AppendEntries":
+		r.handleAppendEntries(msg)
+	case "InstallSnapshot":
+		r.handleInstallSnapshot(msg)
+	case "AppendResponse":
+		r.handleAppendResponse(msg)
+	}
+}
+
+func (r *Raft) handleRequestVote(msg *proto.RaftMessage) {
+	lastTerm := r.termAt(r.lastIndex())
+	upToDate := msg.LogTerm > lastTerm || (msg.LogTerm == lastTerm && msg.LogIndex >= r.lastIndex())
+	grant := msg.Term == r.term && (r.votedFor == "" || r.votedFor == msg.From) && upToDate
+	if grant {
+		r.votedFor = msg.From
+		if err := r.persistTerm(); err != nil {
+			util.Logger.Error().Msgf("failed to log vote for [%s]: [%s]", msg.From, err)
+			grant = false
+		} else {
+			r.resetElectionTimer()
+		}
+	}
+	r.send(&proto.RaftMessage{Type: "Vote", To: msg.From, Term: r.term, Success: grant})
+}
+
+func (r *Raft) handleVote(msg *proto.RaftMessage) {
+	if r.role != raftCandidate || msg.Term != r.term || !msg.Success {
+		return
+	}
+	r.votes[msg.From] = true
+	if len(r.votes) >= r.majority() {
+		r.becomeLeader()
+	}
+}
+
+// follow accepts the sender of a current message as leader.
+func (r *Raft) follow(msg *proto.RaftMessage) bool {
+	if msg.Term < r.term {
+		r.send(&proto.RaftMessage{Type: "AppendResponse", To: msg.From, Term: r.term, Height: r.appliedHeight})
+		return false
+	}
+	r.role = raftFollower
+	r.leaderID = msg.From
+	r.resetElectionTimer()
+	return true
+}
+
+func (r *Raft) handleAppendEntries(msg *proto.RaftMessage) {
+	if !r.follow(msg) {
+		return
+	}
+	reply := &proto.RaftMessage{Type: "AppendResponse", To: msg.From, Term: r.term}
+
+	prevIndex, prevTerm, entries := msg.LogIndex, msg.LogTerm, msg.Entries
+	// entries covered by the snapshot are committed and match by definition
+	for len(entries) > 0 && prevIndex < r.snapshotIndex {
+		prevIndex, prevTerm, entries = entries[0].Index, entries[0].Term, entries[1:]
+	}
+	if prevIndex < r.snapshotIndex {
+		prevIndex, prevTerm = r.snapshotIndex, r.snapshotTerm
+	}
+	if r.termAt(prevIndex) != prevTerm {
+		reply.LogIndex = min(r.lastIndex(), prevIndex-1)
+		reply.Height = r.appliedHeight
+		r.send(reply)
+		return
+	}
+
+	for _, e := range entries {
+		if r.termAt(e.Index) == e.Term {
+			continue
+		}
+		// a conflicting entry and everything after it are replaced
+		if e.Index <= r.lastIndex() {
+			r.log = r.log[:e.Index-r.snapshotIndex-1]
+		}
+		entry := raftEntry{term: e.Term, index: e.Index, block: e.Block}
+		if err := r.persistEntry(entry); err != nil {
+			util.Logger.Error().Msgf("failed to log entry [%d]: [%s]", e.Index, err)
+			reply.LogIndex = r.lastIndex()
+			reply.Height = r.appliedHeight
+			r.send(reply)
+			return
+		}
+		r.log = append(r.log, entry)
+	}
+
+	lastNew := prevIndex + int64(len(entries))
+	if msg.CommitIndex > r.commitIndex {
+		r.commitIndex = min(msg.CommitIndex, lastNew)
+	}
+	r.apply()
+
+	reply.Success = true
+	reply.LogIndex = lastNew
+	reply.Height = r.appliedHeight
+	r.send(reply)
+}
+
+// handleInstallSnapshot applies the blocks a leader sends from its store to a
+// follower behind its snapshot. The snapshot is installed with the last batch.
+func (r *Raft) handleInstallSnapshot(msg *proto.RaftMessage) {
+	if !r.follow(msg) {
+		return
+	}
+	for _, e := range msg.Entries {
+		if e.Block == nil || int64(blockSequence(e.Block)) != r.appliedHeight+1 {
+			continue
+		}
+		r.appliedHeight++
+		r.committed <- Commit{Block: e.Block, View: int(msg.Term)}
+	}
+
+	reply := &proto.RaftMessage{Type: "AppendResponse", To: msg.From, Term: r.term, Height: r.appliedHeight}
+	if r.appliedHeight >= msg.Height && msg.LogIndex > r.snapshotIndex {
+		if r.termAt(msg.LogIndex) == msg.LogTerm {
+			r.log = r.log[msg.LogIndex-r.snapshotIndex:]
+		} else {
+			r.log = nil
+		}
+		r.compact(msg.LogIndex, msg.LogTerm, msg.Height)
+		r.commitIndex = max(r.commitIndex, msg.LogIndex)
+		r.lastApplied = max(r.lastApplied, msg.LogIndex)
+		util.Logger.Info().Msgf("installed snapshot at index [%d] height [%d]", msg.LogIndex, msg.Height)
+	}
+	if r.appliedHeight >= msg.Height {
+		reply.Success = true
+		reply.LogIndex = r.snapshotIndex
+	}
+	r.send(reply)
+}
+
+func (r *Raft) handleAppendResponse(msg *proto.RaftMessage) {
+	if r.role != raftLeader || msg.Term != r.term {
+		return
+	}
+	r.peerHeight[msg.From] = msg.Height
+	if msg.Success {
+		r.matchIndex[msg.From] = max(r.matchIndex[msg.From], msg.LogIndex)
+		r.nextIndex[msg.From] = r.matchIndex[msg.From] + 1
+		r.advanceCommit()
+		return
+	}
+	r.nextIndex[msg.From] = max(1, min(r.nextIndex[msg.From]-1, msg.LogIndex+1))
+	r.replicate(msg.From)
+}
+
+func (r *Raft) replicateAll() {
+	for _, peer := range r.peers {
+		if peer != r.id {
+			r.replicate(peer)
+		}
+	}
+}
+
+// replicate sends a follower the entries it is missing, or the blocks of the
+// snapshot when those entries were compacted.
+func (r *Raft) replicate(peer string) {
+	next := max(r.nextIndex[peer], 1)
+	if next <= r.snapshotIndex && r.blocks != nil {
+		msg := &proto.RaftMessage{
+			Type:     "InstallSnapshot",
+			To:       peer,
+			Term:     r.term,
+			LogIndex: r.snapshotIndex,
+			LogTerm:  r.snapshotTerm,
+			Height:   r.snapshotHeight,
+		}
+		for h := r.peerHeight[peer] + 1; h <= r.snapshotHeight && len(msg.Entries) < raftBatchSize; h++ {
+			b, err := r.blocks.GetBlockByHeight(int(h))
+			if err != nil {
+				util.Logger.Error().Msgf("failed to read block [%d] for snapshot: [%s]", h, err)
+				break
+			}
+			msg.Entries = append(msg.Entries, &proto.RaftEntry{Block: b})
+		}
+		r.send(msg)
+		return
+	}
+	next = max(next, r.snapshotIndex+1)
+
+	msg := &proto.RaftMessage{
+		Type:        "AppendEntries",
+		To:          peer,
+		Term:        r.term,
+		LogIndex:    next - 1,
+		LogTerm:     r.termAt(next - 1),
+		CommitIndex: r.commitIndex,
+	}
+	for i := next; i <= r.lastIndex() && len(msg.Entries) < raftBatchSize; i++ {
+		e := r.entry(i)
+		msg.Entries = append(msg.Entries, &proto.RaftEntry{Term: e.term, Index: e.index, Block: e.block})
+	}
+	r.send(msg)
+}
+
+// advanceCommit commits the newest entry of the current term a majority stores.
+func (r *Raft) advanceCommit() {
+	for n := r.lastIndex(); n > r.commitIndex && r.termAt(n) == r.term; n-- {
+		count := 1
+		for _, peer := range r.peers {
+			if peer != r.id && r.matchIndex[peer] >= n {
+				count++
+			}
+		}
+		if count >= r.majority() {
+			r.commitIndex = n
+			break
+		}
+	}
+	r.apply()
+}
+
+// apply delivers the blocks of committed entries in log order.
+func (r *Raft) apply() {
+	for r.lastApplied < r.commitIndex {
+		r.lastApplied++
+		e := r.entry(r.lastApplied)
+		if e.block == nil || int64(blockSequence(e.block)) <= r.appliedHeight {
+			continue
+		}
+		r.appliedHeight = int64(blockSequence(e.block))
+		util.Logger.Info().Msgf("finalized block [%s] at index [%d] term [%d]", hex.EncodeToString(types.HashBlock(e.block))[:8], e.index, e.term)
+		r.committed <- Commit{Block: e.block, View: int(e.term)}
+	}
+	r.maybeSnapshot()
+}
+
+// maybeSnapshot compacts applied entries whose blocks the node has persisted.
+func (r *Raft) maybeSnapshot() {
+	if r.blocks == nil || r.lastApplied-r.snapshotIndex < raftSnapshotInterval {
+		return
+	}
+
+	var (
+		persisted = int64(r.blocks.Height())
+		index     = r.snapshotIndex
+		height    = r.snapshotHeight
+	)
+	for i := r.snapshotIndex + 1; i <= r.lastApplied; i++ {
+		if b := r.entry(i).block; b != nil {
+			if int64(blockSequence(b)) > persisted {
+				break
+			}
+			height = max(height, int64(blockSequence(b)))
+		}
+		index = i
+	}
+	if index == r.snapshotIndex {
+		return
+	}
+
+	term := r.termAt(index)
+	r.log = r.log[index-r.snapshotIndex:]
+	r.compact(index, term, height)
+	util.Logger.Info().Msgf("compacted raft log up to index [%d] height [%d]", index, height)
+}
+
+// compact moves the snapshot to index, the log must already start after it.
+func (r *Raft) compact(index, term, height int64) {
+	r.snapshotIndex, r.snapshotTerm, r.snapshotHeight = index, term, height
+	if r.wal == nil {
+		return
+	}
+
+	// the rewritten log holds the snapshot, the term and the remaining entries,
+	// it replaces the old one at once so the vote of the term is never lost
+	records := []ConsensusMessage{{
+		Type:     walSnapshot,
+		View:     int(term),
+		Sequence: int(index),
+		Block:    &proto.Block{Header: &proto.Header{Height: int32(height)}},
+	}, r.termRecord()}
+	for _, e := range r.log {
+		records = append(records, entryRecord(e))
+	}
+	if err := r.wal.Rewrite(records); err != nil {
+		util.Logger.Error().Msgf("failed to compact raft log: [%s]", err)
+	}
+}
+
+func (r *Raft) persistTerm() error {
+	if r.wal == nil {
+		return nil
+	}
+	return r.wal.Write(r.termRecord())
+}
+
+func (r *Raft) persistEntry(e raftEntry) error {
+	if r.wal == nil {
+		return nil
+	}
+	return r.wal.Write(entryRecord(e))
+}
+
+func (r *Raft) termRecord() ConsensusMessage {
+	return ConsensusMessage{Type: walTerm, View: int(r.term), NodeID: r.votedFor}
+}
+
+func entryRecord(e raftEntry) ConsensusMessage {
+	return ConsensusMessage{Type: walEntry, View: int(e.term), Sequence: int(e.index), Block: e.block}
+}
+
+// replay restores the term, the vote and the log recorded in the WAL. A later
+// entry at an index replaces the earlier one and everything after it.
+func (r *Raft) replay() {
+	if r.wal == nil {
+		return
+	}
+	records, err := r.wal.Replay()
+	if err != nil {
+		util.Logger.Error().Msgf("failed to replay raft log: [%s]", err)
+		return
+	}
+
+	entries := []raftEntry{}
+	for _, rec := range records {
+		switch rec.Type {
+		case walTerm:
+			r.term, r.votedFor = int64(rec.View), rec.NodeID
+		case walEntry:
+			index := int64(rec.Sequence)
+			for len(entries) > 0 && entries[len(entries)-1].index >= index {
+				entries = entries[:len(entries)-1]
+			}
+			entries = append(entries, raftEntry{term: int64(rec.View), index: index, block: rec.Block})
+		case walSnapshot:
+			r.snapshotIndex, r.snapshotTerm = int64(rec.Sequence), int64(rec.View)
+			r.snapshotHeight = int64(blockSequence(rec.Block))
+		}
+	}
+
+	// only the entries that continue the snapshot form the log
+	next := r.snapshotIndex + 1
+	for _, e := range entries {
+		if e.index < next {
+			continue
+		}
+		if e.index != next {
+			break
+		}
+		r.log = append(r.log, e)
+		next++
+	}
+	r.commitIndex, r.lastApplied = r.snapshotIndex, r.snapshotIndex
+	r.appliedHeight = r.snapshotHeight
+	util.Logger.Info().Msgf("replayed raft log, term [%d] snapshot [%d] last index [%d]", r.term, r.snapshotIndex, r.lastIndex())
+}
+
+// send signs a raft message and hands it to the transport.
+func (r *Raft) send(msg *proto.RaftMessage) {
+	msg.From = r.id
+	msg.Signature = r.privKey.Sign(raftMessageHash(msg)).Bytes()
+	if r.transport == nil {
+		return
+	}
+	if err := r.transport.SendRaftMessage(msg); err != nil {
+		util.Logger.Error().Msgf("failed to send [%s] raft message to [%s]: [%s]", msg.Type, msg.To, err)
+	}
+}
+
+func (r *Raft) verify(msg *proto.RaftMessage) bool {
+	pubKey := r.known[msg.From]
+	if pubKey == nil || len(msg.Signature) != crypto.SignatureLen {
+		return false
+	}
+	return crypto.SignatureFromBytes(msg.Signature).Verify(pubKey, raftMessageHash(msg))
+}
+
+// raftMessageHash returns the hash signed for a raft message, the deterministic
+// encoding of the message without its signature.
+func raftMessageHash(msg *proto.RaftMessage) []byte {
+	unsigned := pb.Clone(msg).(*proto.RaftMessage)
+	unsigned.Signature = nil
+	data, err := pb.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+	if err != nil {
+		util.Logger.Error().Msgf("failed to encode raft message: [%s]", err)
+	}
+
+	hash := sha3.New512()
+	hash.Write(data)
+
+	return hash.Sum(nil)
+}
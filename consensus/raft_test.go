@@ -0,0 +1,285 @@
+package consensus
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// raftNetwork delivers raft messages between engines of one process.
+type raftNetwork struct {
+	lock    sync.RWMutex
+	engines map[string]*Raft
+	down    map[string]bool
+	stopped map[string]bool
+}
+
+type raftTransport struct {
+	network *raftNetwork
+	nodeID  string
+}
+
+func (t *raftTransport) SendRaftMessage(msg *proto.RaftMessage) error {
+	t.network.lock.RLock()
+	defer t.network.lock.RUnlock()
+
+	if t.network.down[t.nodeID] {
+		return nil
+	}
+	for id, engine := range t.network.engines {
+		if id == t.nodeID || t.network.down[id] || msg.To != "" && msg.To != id {
+			continue
+		}
+		go engine.OnReceiveRaftMessage(msg)
+	}
+	return nil
+}
+
+// stop stops an engine, its state can be inspected once the run loop returned.
+func (n *raftNetwork) stop(id string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if !n.stopped[id] {
+		n.stopped[id] = true
+		n.engines[id].Stop()
+	}
+	time.Sleep(50 * time.Millisecond)
+}
+
+func (n *raftNetwork) setDown(id string, down bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.down[id] = down
+}
+
+// memoryBlocks is a block store filled from the commits of an engine.
+type memoryBlocks struct {
+	lock   sync.Mutex
+	blocks map[int]*proto.Block
+}
+
+func (s *memoryBlocks) Height() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.blocks)
+}
+
+func (s *memoryBlocks) GetBlockByHeight(height int) (*proto.Block, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if b, ok := s.blocks[height]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("block %d not found", height)
+}
+
+func (s *memoryBlocks) put(b *proto.Block) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.blocks[blockSequence(b)] = b
+}
+
+func newRaftNetwork(t *testing.T, n int) (*raftNetwork, []*crypto.PrivateKey) {
+	var (
+		network    = &raftNetwork{engines: make(map[string]*Raft), down: make(map[string]bool), stopped: make(map[string]bool)}
+		keys       = make([]*crypto.PrivateKey, n)
+		validators = make([]*crypto.PublicKey, n)
+	)
+	for i := range keys {
+		keys[i] = crypto.GeneratePrivateKey()
+		validators[i] = keys[i].Public()
+	}
+	for _, key := range keys {
+		id := key.Public().String()
+		engine := NewRaft(validators, key, &raftTransport{network: network, nodeID: id}, nil, nil)
+		engine.electionTimeout = 150 * time.Millisecond
+		engine.heartbeat = 30 * time.Millisecond
+		network.engines[id] = engine
+	}
+	t.Cleanup(func() {
+		for id := range network.engines {
+			if !network.stopped[id] {
+				network.engines[id].Stop()
+			}
+		}
+	})
+	return network, keys
+}
+
+// proposeToLeader offers a block to all live engines until the leader takes it.
+func proposeToLeader(t *testing.T, network *raftNetwork, b *proto.Block) string {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for id, engine := range network.engines {
+			network.lock.RLock()
+			down := network.down[id]
+			network.lock.RUnlock()
+			if !down && engine.ProposeBlock(b) == nil {
+				return id
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("no leader took the block")
+	return ""
+}
+
+func TestRaftSingleValidatorCommits(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	engine := NewRaft(nil, privKey, nil, nil, nil)
+	engine.electionTimeout = 50 * time.Millisecond
+	engine.Start()
+	defer engine.Stop()
+
+	network := &raftNetwork{engines: map[string]*Raft{privKey.Public().String(): engine}, down: map[string]bool{}}
+	block := nextBlock(t, privKey, nil)
+	proposeToLeader(t, network, block)
+
+	c := waitCommit(t, engine)
+	assert.Equal(t, types.HashBlock(block), types.HashBlock(c.Block))
+	assert.ErrorIs(t, engine.ProposeBlock(block), ErrStaleSequence)
+}
+
+func TestRaftReplicatesAndSurvivesLeaderCrash(t *testing.T) {
+	network, keys := newRaftNetwork(t, 3)
+	for _, engine := range network.engines {
+		engine.Start()
+	}
+
+	first := nextBlock(t, keys[0], nil)
+	leader := proposeToLeader(t, network, first)
+	for _, engine := range network.engines {
+		c := waitCommit(t, engine)
+		assert.Equal(t, types.HashBlock(first), types.HashBlock(c.Block))
+	}
+
+	// the remaining majority elects a new leader that keeps the log
+	network.setDown(leader, true)
+	second := nextBlock(t, keys[1], first)
+	newLeader := proposeToLeader(t, network, second)
+	assert.NotEqual(t, leader, newLeader)
+	for id, engine := range network.engines {
+		if id == leader {
+			continue
+		}
+		c := waitCommit(t, engine)
+		assert.Equal(t, types.HashBlock(second), types.HashBlock(c.Block))
+	}
+}
+
+func TestRaftFollowerCatchesUpFromSnapshot(t *testing.T) {
+	network, keys := newRaftNetwork(t, 3)
+	stores := make(map[string]*memoryBlocks)
+	for id, engine := range network.engines {
+		stores[id] = &memoryBlocks{blocks: make(map[int]*proto.Block)}
+		engine.blocks = stores[id]
+	}
+
+	// one follower is away while enough blocks are committed to compact the log
+	lagging := keys[2].Public().String()
+	network.setDown(lagging, true)
+	for id, engine := range network.engines {
+		engine.Start()
+		if id == lagging {
+			continue
+		}
+		go func(id string, engine *Raft) {
+			for c := range engine.Committed() {
+				stores[id].put(c.Block)
+			}
+		}(id, engine)
+	}
+
+	var (
+		leader string
+		block  *proto.Block
+	)
+	for height := 1; height <= raftSnapshotInterval+5; height++ {
+		block = nextBlock(t, keys[0], block)
+		leader = proposeToLeader(t, network, block)
+		require.Eventually(t, func() bool { return stores[leader].Height() == height }, 5*time.Second, time.Millisecond)
+	}
+
+	// back online, the follower gets the compacted blocks from the leader's store
+	network.setDown(lagging, false)
+	for height := 1; height <= raftSnapshotInterval+5; height++ {
+		c := waitCommit(t, network.engines[lagging])
+		require.Equal(t, height, blockSequence(c.Block))
+	}
+
+	// the blocks were compacted out of the leader's log, only the snapshot had them
+	network.stop(leader)
+	assert.Greater(t, network.engines[leader].snapshotIndex, int64(raftSnapshotInterval/2))
+}
+
+func TestRaftLogSurvivesRestart(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		path    = filepath.Join(t.TempDir(), "raft.wal")
+	)
+	wal, err := OpenFileWAL(path)
+	require.Nil(t, err)
+	engine := NewRaft(nil, privKey, nil, wal, nil)
+	engine.electionTimeout = 50 * time.Millisecond
+	engine.Start()
+
+	network := &raftNetwork{engines: map[string]*Raft{privKey.Public().String(): engine}, down: map[string]bool{}}
+	proposeToLeader(t, network, nextBlock(t, privKey, nil))
+	waitCommit(t, engine)
+	engine.Stop()
+	time.Sleep(20 * time.Millisecond)
+	require.Nil(t, wal.Close())
+
+	wal, err = OpenFileWAL(path)
+	require.Nil(t, err)
+	defer wal.Close()
+	restarted := NewRaft(nil, privKey, nil, wal, nil)
+	restarted.replay()
+	assert.Equal(t, engine.term, restarted.term)
+	assert.Equal(t, privKey.Public().String(), restarted.votedFor)
+	assert.Equal(t, int64(2), restarted.lastIndex()) // the no-op of the term and the block
+	assert.Equal(t, 1, blockSequence(restarted.entry(2).block))
+}
+
+func TestRaftCompactionKeepsTheVote(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		path    = filepath.Join(t.TempDir(), "raft.wal")
+		first   = nextBlock(t, privKey, nil)
+		second  = nextBlock(t, privKey, first)
+	)
+	wal, err := OpenFileWAL(path)
+	require.Nil(t, err)
+	engine := NewRaft(nil, privKey, nil, wal, nil)
+	engine.term, engine.votedFor = 3, privKey.Public().String()
+	require.Nil(t, engine.persistTerm())
+	require.Nil(t, engine.appendEntry(first))
+	require.Nil(t, engine.appendEntry(second))
+
+	engine.log = engine.log[1:]
+	engine.compact(1, 3, 1)
+	require.Nil(t, wal.Close())
+
+	wal, err = OpenFileWAL(path)
+	require.Nil(t, err)
+	defer wal.Close()
+	restarted := NewRaft(nil, privKey, nil, wal, nil)
+	restarted.replay()
+	assert.Equal(t, int64(3), restarted.term)
+	assert.Equal(t, privKey.Public().String(), restarted.votedFor)
+	assert.Equal(t, int64(1), restarted.snapshotIndex)
+	assert.Equal(t, int64(2), restarted.lastIndex())
+	assert.Equal(t, types.HashBlock(second), types.HashBlock(restarted.entry(2).block))
+
+	// a block has to extend the last one of the log
+	assert.False(t, restarted.ValidateBlock(nextBlock(t, privKey, first)))
+	assert.True(t, restarted.ValidateBlock(nextBlock(t, privKey, second)))
+}
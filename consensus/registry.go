@@ -0,0 +1,101 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+)
+
+// DefaultEngine is used when the configuration does not name an engine.
+const DefaultEngine = "pbft"
+
+// Options carries what the node hands to a consensus engine.
+type Options struct {
+	Validators   []*crypto.PublicKey
+	PrivateKey   *crypto.PrivateKey
+	Transport    Transport
+	WAL          WAL            // only used by engines that keep state across restarts
+	SlotDuration time.Duration  // block time, used by engines with time slots
+	Blocks       BlockSource    // persisted blocks, used by engines that compact their log
+	BlockRules   BlockValidator // chain rules a proposed block is checked against, may be nil
+
+	// ValidatorSet is the on-chain validator set, engines that follow
+	// governance start from it instead of Validators when it is set
+	ValidatorSet *proto.ValidatorSet
+}
+
+// Factory creates a consensus engine.
+type Factory func(opts Options) (Consensus, error)
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]Factory)
+)
+
+// Register makes an engine available under a name, registering a name twice panics.
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("consensus engine [%s] registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// New creates the engine registered under name.
+func New(name string, opts Options) (Consensus, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+
+	registryLock.RLock()
+	factory, ok := registry[name]
+	registryLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown consensus engine [%s], available %v", name, Engines())
+	}
+	return factory(opts)
+}
+
+// Engines returns the names of all registered engines.
+func Engines() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register("pbft", func(opts Options) (Consensus, error) {
+		engine := NewPBFTPoA(opts.Validators, opts.PrivateKey, opts.Transport, opts.WAL)
+		if opts.ValidatorSet != nil {
+			engine.SetValidatorSet(opts.ValidatorSet)
+		}
+		engine.SetBlockValidator(opts.BlockRules)
+		return engine, nil
+	})
+	Register("solo", func(opts Options) (Consensus, error) {
+		return NewSolo(opts.Validators, opts.PrivateKey), nil
+	})
+	Register("poa", func(opts Options) (Consensus, error) {
+		return NewRoundRobinPoA(opts.Validators, opts.PrivateKey, opts.Transport, opts.SlotDuration), nil
+	})
+	Register("raft", func(opts Options) (Consensus, error) {
+		transport, ok := opts.Transport.(RaftTransport)
+		if opts.Transport != nil && !ok {
+			return nil, errors.New("transport does not deliver raft messages")
+		}
+		return NewRaft(opts.Validators, opts.PrivateKey, transport, opts.WAL, opts.Blocks), nil
+	})
+}
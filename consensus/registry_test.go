@@ -0,0 +1,33 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEngine(t *testing.T) {
+	opts := Options{PrivateKey: crypto.GeneratePrivateKey()}
+
+	engine, err := New("", opts)
+	require.Nil(t, err)
+	assert.IsType(t, &PBFTPoA{}, engine)
+
+	engine, err = New("solo", opts)
+	require.Nil(t, err)
+	assert.IsType(t, &Solo{}, engine)
+
+	engine, err = New("poa", opts)
+	require.Nil(t, err)
+	assert.IsType(t, &RoundRobinPoA{}, engine)
+
+	engine, err = New("raft", opts)
+	require.Nil(t, err)
+	assert.IsType(t, &Raft{}, engine)
+
+	_, err = New("paxos", opts)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"pbft", "poa", "raft", "solo"}, Engines())
+}
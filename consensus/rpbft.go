@@ -1,51 +1,177 @@
 package consensus
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/janrockdev/darkblock/crypto"
 	"github.com/janrockdev/darkblock/proto"
 	"github.com/janrockdev/darkblock/types"
 	"github.com/janrockdev/darkblock/util"
 )
 
+const (
+	// viewChangeTimeout is how long a validator waits for a pending block to be
+	// committed before it suspects the leader and starts a view change.
+	viewChangeTimeout = 10 * time.Second
+)
+
+var (
+	// ErrProposalInFlight is returned when the leader is still waiting for the previous block to commit.
+	ErrProposalInFlight = errors.New("previous block proposal is not committed yet")
+	// ErrStaleSequence is returned for a block whose height was already committed.
+	ErrStaleSequence = errors.New("block sequence already committed")
+)
+
+// slot identifies one consensus instance by view and sequence number.
+type slot struct {
+	view     int
+	sequence int
+}
+
+func slotOf(msg ConsensusMessage) slot {
+	return slot{view: msg.View, sequence: msg.Sequence}
+}
+
+// proposal is a block handed to the engine together with the channel its outcome is reported on.
+type proposal struct {
+	block  *proto.Block
+	result chan error
+}
+
 type PBFTPoA struct {
 	mu          sync.Mutex
-	validators  []string // <---- review list of node public keys / ports
+	validators  []string // hex encoded public keys, ordered for leader rotation
+	known       map[string]*crypto.PublicKey
 	quorumSize  int
 	currentView int
 	isValidator bool
 	nodeID      string
+	privKey     *crypto.PrivateKey
+
+	// Buffers for messages, votes are keyed by slot and node ID
+	prePrepareMsgs map[slot]*proto.Block
+	prepareVotes   map[slot]map[string]ConsensusMessage
+	commitVotes    map[slot]map[string]ConsensusMessage
+	committedSlots map[slot]bool
+	lastCommitted  int
+	lastBlock      []byte // hash of the block of lastCommitted, nil until one commits after a start
+
+	// blockRules checks proposed blocks against the chain of the node
+	blockRules BlockValidator
+
+	// Checkpoint state, messages are only accepted between the watermarks
+	checkpoints      map[int]map[string]ConsensusMessage
+	stableCheckpoint int
+	interval         int
+	window           int
+
+	// Equivocation, validators are removed once evidence against them is committed
+	evidence chan *proto.Evidence
+	accused  map[string]bool
+
+	// On-chain validator set, changed by evidence and governance in committed blocks
+	validatorSet *proto.ValidatorSet
+
+	// View change state
+	viewChanges  map[int]map[string]ConsensusMessage
+	newViewSent  map[int]bool
+	viewChanging bool
+	targetView   int
+	pending      *proto.Block // in-flight block waiting for a leader to commit it
+	prepared     *preparedCertificate
+	viewTimer    *time.Timer
+	timerRunning bool
+	timeout      time.Duration
+
+	blockProposals chan proposal
+	incomingMsgs   chan ConsensusMessage
+	committed      chan Commit
+	started        atomic.Bool // messages are dropped until the engine runs
 
-	// Buffers for messages
-	prePrepareMsgs map[int]*proto.Block
-	prepareVotes   map[int]map[string]bool
-	commitVotes    map[int]map[string]bool
+	transport Transport
+	wal       WAL
 
-	blockProposals chan *proto.Block
-	incomingMsgs   chan ConsensusMessage
+	// own messages waiting to be handled, they are delivered after the message
+	// that produced them so the run loop never re-enters itself
+	selfMsgs []ConsensusMessage
 
 	stopCh chan struct{}
 }
 
-func NewPBFTPoA(validators []string) *PBFTPoA {
+// NewPBFTPoA creates a PBFT engine for the given validator set. The node is
+// identified by the public key of privKey, an empty validator set makes the
+// node the only validator (development only). The state recorded in wal, if
+// any, is restored on Start.
+func NewPBFTPoA(validators []*crypto.PublicKey, privKey *crypto.PrivateKey, transport Transport, wal WAL) *PBFTPoA {
+	viewTimer := time.NewTimer(viewChangeTimeout)
+	viewTimer.Stop()
+
+	nodeID := privKey.Public().String()
+	if len(validators) == 0 {
+		validators = []*crypto.PublicKey{privKey.Public()}
+	}
+
+	var (
+		ids   = make([]string, 0, len(validators))
+		known = make(map[string]*crypto.PublicKey, len(validators))
+	)
+	for _, v := range validators {
+		if _, ok := known[v.String()]; ok {
+			continue
+		}
+		ids = append(ids, v.String())
+		known[v.String()] = v
+	}
+
 	return &PBFTPoA{
-		validators:     validators,
-		quorumSize:     len(validators), // better option 2*len(validators))/3 + 1, // PBFT quorum
+		validators:     ids,
+		known:          known,
+		quorumSize:     types.QuorumSize(len(ids)),
 		currentView:    0,
-		isValidator:    true,         // <---- review hardcoded value for a single node instance - development only
-		nodeID:         "validator1", // <---- review hardcoded value for a single node instance - development only
-		prePrepareMsgs: make(map[int]*proto.Block),
-		prepareVotes:   make(map[int]map[string]bool),
-		commitVotes:    make(map[int]map[string]bool),
-		blockProposals: make(chan *proto.Block),
+		isValidator:    known[nodeID] != nil,
+		nodeID:         nodeID,
+		privKey:        privKey,
+		prePrepareMsgs: make(map[slot]*proto.Block),
+		prepareVotes:   make(map[slot]map[string]ConsensusMessage),
+		commitVotes:    make(map[slot]map[string]ConsensusMessage),
+		committedSlots: make(map[slot]bool),
+		checkpoints:    make(map[int]map[string]ConsensusMessage),
+		interval:       checkpointInterval,
+		window:         watermarkWindow,
+		evidence:       make(chan *proto.Evidence, 16),
+		accused:        make(map[string]bool),
+		validatorSet:   types.NewValidatorSet(validators),
+		viewChanges:    make(map[int]map[string]ConsensusMessage),
+		newViewSent:    make(map[int]bool),
+		viewTimer:      viewTimer,
+		timeout:        viewChangeTimeout,
+		blockProposals: make(chan proposal),
 		incomingMsgs:   make(chan ConsensusMessage, 100),
+		committed:      make(chan Commit, 16),
+		transport:      transport,
+		wal:            wal,
 		stopCh:         make(chan struct{}),
 	}
 }
 
+// SetBlockValidator makes the engine check proposed blocks against the chain
+// rules of the node before voting for them. It must be called before Start.
+func (p *PBFTPoA) SetBlockValidator(rules BlockValidator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.blockRules = rules
+}
+
 func (p *PBFTPoA) Start() {
 	util.Logger.Info().Msg("starting RpBFT consensus protocol")
+	p.replay()
+	p.started.Store(true)
 	go p.run()
 }
 
@@ -58,49 +184,144 @@ func (p *PBFTPoA) run() {
 	for {
 		select {
 		case <-p.stopCh:
+			p.viewTimer.Stop()
 			return
-		case b := <-p.blockProposals:
-			util.Logger.Info().Msgf("received block proposal [%s]", hex.EncodeToString(types.HashBlock(b))[:3])
-			if p.isLeader() {
-				p.handleProposeBlock(b)
-			}
+		case prop := <-p.blockProposals:
+			util.Logger.Info().Msgf("received block proposal [%s]", hex.EncodeToString(types.HashBlock(prop.block))[:3])
+			prop.result <- p.handleProposal(prop.block)
 		case msg := <-p.incomingMsgs:
-			util.Logger.Info().Msgf("received incomming message [%s]", hex.EncodeToString(types.HashBlock(msg.Block)[:3]))
+			util.Logger.Info().Msgf("received incomming [%s] message in view [%d] sequence [%d]", msg.Type, msg.View, msg.Sequence)
 			p.handleMessage(msg)
+		case <-p.viewTimer.C:
+			p.handleViewTimeout()
+			p.deliverOwn()
 		}
 	}
 }
 
+// deliverOwn handles the messages this node sent, and the ones they lead to,
+// until none is left.
+func (p *PBFTPoA) deliverOwn() {
+	for len(p.selfMsgs) > 0 {
+		msg := p.selfMsgs[0]
+		p.selfMsgs = p.selfMsgs[1:]
+		p.dispatch(msg)
+	}
+	p.selfMsgs = nil
+}
+
 func (p *PBFTPoA) isLeader() bool {
-	return true // <---- review p.validators[p.currentView%len(p.validators)] == p.nodeID
+	return p.leader(p.currentView) == p.nodeID
+}
+
+// leader returns the node expected to propose blocks in the given view. The
+// leader rotates round-robin over the validator set on every view change.
+func (p *PBFTPoA) leader(view int) string {
+	return p.validators[view%len(p.validators)]
 }
 
+// Committed returns the channel on which finalized blocks are delivered.
+func (p *PBFTPoA) Committed() <-chan Commit {
+	return p.committed
+}
+
+// ProposeBlock hands a block template to the engine. The current leader
+// proposes it, other validators keep it as the in-flight block and watch the
+// leader for progress. An error means the block was not taken and its
+// transactions should be proposed again later.
 func (p *PBFTPoA) ProposeBlock(b *proto.Block) error {
-	// Non-leaders just queue block proposals. They won't actually propose.
-	if !p.isLeader() {
+	result := make(chan error, 1)
+	select {
+	case p.blockProposals <- proposal{block: b, result: result}:
+	case <-p.stopCh:
+		return errors.New("consensus engine stopped")
+	}
+	return <-result
+}
+
+func (p *PBFTPoA) handleProposal(b *proto.Block) error {
+	if !p.isValidator {
+		return nil
+	}
+	if blockSequence(b) <= p.lastCommitted {
+		return ErrStaleSequence
+	}
+	if !p.isLeader() || p.viewChanging {
+		p.keepPending(b)
 		return nil
 	}
-	p.blockProposals <- b
+	// blocks are chained, the next one can only be proposed once the previous one commits
+	for s := range p.prePrepareMsgs {
+		if s.view == p.currentView && !p.committedSlots[s] {
+			return ErrProposalInFlight
+		}
+	}
+	p.handleProposeBlock(b)
+	p.deliverOwn()
 	return nil
 }
 
 func (p *PBFTPoA) handleProposeBlock(b *proto.Block) {
 	// Create a Pre-Prepare message and broadcast it
 	msg := ConsensusMessage{
-		Type:   "PrePrepare",
-		Block:  b,
-		View:   p.currentView,
-		NodeID: p.nodeID,
+		Type:     "PrePrepare",
+		Block:    b,
+		View:     p.currentView,
+		Sequence: blockSequence(b),
+		NodeID:   p.nodeID,
 	}
-	util.Logger.Info().Msgf("proposing block [%s] msg [%d]\n", hex.EncodeToString(types.HashBlock(b))[:3], p.currentView)
+	util.Logger.Info().Msgf("proposing block [%s] view [%d] sequence [%d]", hex.EncodeToString(types.HashBlock(b))[:3], msg.View, msg.Sequence)
 	p.broadcast(msg)
 }
 
+// keepPending remembers a block a non-leader could not propose and starts the
+// view change timer, the leader is expected to commit a block before it fires.
+func (p *PBFTPoA) keepPending(b *proto.Block) {
+	if p.pending == nil || blockSequence(p.pending) <= p.lastCommitted {
+		p.pending = b
+	}
+	p.startViewTimer()
+}
+
+// OnReceiveMessage queues a message for the run loop without waiting, a
+// message that arrives before the engine started or while the queue is full is
+// dropped. The sender is never held up by this validator.
 func (p *PBFTPoA) OnReceiveMessage(msg ConsensusMessage) {
-	p.incomingMsgs <- msg
+	if !p.started.Load() {
+		return
+	}
+	select {
+	case p.incomingMsgs <- msg:
+	default:
+		util.Logger.Warn().Msgf("dropping [%s] message from [%s], the queue is full", msg.Type, msg.NodeID)
+	}
 }
 
+// handleMessage handles a message and then the own messages it caused.
 func (p *PBFTPoA) handleMessage(msg ConsensusMessage) {
+	p.dispatch(msg)
+	p.deliverOwn()
+}
+
+func (p *PBFTPoA) dispatch(msg ConsensusMessage) {
+	// only votes of known validators are counted
+	if p.known[msg.NodeID] == nil {
+		util.Logger.Warn().Msgf("dropping [%s] message from unknown validator [%s]", msg.Type, msg.NodeID)
+		return
+	}
+	if !msg.Verify(p.known[msg.NodeID]) {
+		util.Logger.Warn().Msgf("dropping [%s] message with invalid signature from [%s]", msg.Type, msg.NodeID)
+		return
+	}
+
+	switch msg.Type {
+	case "PrePrepare", "Prepare", "Commit":
+		if !p.inWindow(msg.Sequence) {
+			util.Logger.Debug().Msgf("dropping [%s] message with sequence [%d] outside of watermarks", msg.Type, msg.Sequence)
+			return
+		}
+	}
+
 	switch msg.Type {
 	case "PrePrepare":
 		p.handlePrePrepare(msg)
@@ -108,101 +329,273 @@ func (p *PBFTPoA) handleMessage(msg ConsensusMessage) {
 		p.handlePrepare(msg)
 	case "Commit":
 		p.handleCommit(msg)
+	case "Checkpoint":
+		p.handleCheckpoint(msg)
+	case "ViewChange":
+		p.handleViewChange(msg)
+	case "NewView":
+		p.handleNewView(msg)
 	}
 }
 
 func (p *PBFTPoA) handlePrePrepare(msg ConsensusMessage) {
-	// Validate leader
-	if p.validators[p.currentView%len(p.validators)] != msg.NodeID {
+	// Validate leader and view
+	if msg.View != p.currentView || p.viewChanging || p.leader(msg.View) != msg.NodeID {
 		return
 	}
+	// a leader proposing two blocks for one slot signed both of them
+	if prev := p.prePrepareMsgs[slotOf(msg)]; prev != nil && msg.Block != nil {
+		p.reportEvidence(types.NewBlockEvidence(prev, msg.Block))
+	}
+	p.acceptPrePrepare(slotOf(msg), msg.Block)
+}
+
+// acceptPrePrepare stores the block proposed for a slot and votes for it.
+func (p *PBFTPoA) acceptPrePrepare(s slot, b *proto.Block) {
 	// Validate block
-	if !p.ValidateBlock(msg.Block) {
+	if b == nil || blockSequence(b) != s.sequence || s.sequence <= p.lastCommitted || !p.ValidateBlock(b) {
+		return
+	}
+	// a leader proposing two blocks for one slot is ignored after the first
+	if p.prePrepareMsgs[s] != nil {
 		return
 	}
 
-	p.prePrepareMsgs[msg.View] = msg.Block
-	p.prepareVotes[msg.View] = map[string]bool{p.nodeID: true}
+	p.prePrepareMsgs[s] = b
+	p.startViewTimer()
 
 	// Broadcast Prepare
 	prepareMsg := ConsensusMessage{
-		Type:   "Prepare",
-		Block:  msg.Block,
-		View:   msg.View,
-		NodeID: p.nodeID,
+		Type:     "Prepare",
+		Block:    b,
+		View:     s.view,
+		Sequence: s.sequence,
+		NodeID:   p.nodeID,
 	}
 	p.broadcast(prepareMsg)
+
+	// votes may have arrived before the pre-prepare
+	p.checkPrepared(s)
+	p.checkCommitted(s)
 }
 
 func (p *PBFTPoA) handlePrepare(msg ConsensusMessage) {
-	// Must have a pre-prepare for this view
-	if p.prePrepareMsgs[msg.View] == nil {
+	p.addVote(p.prepareVotes, msg)
+	p.checkPrepared(slotOf(msg))
+}
+
+func (p *PBFTPoA) handleCommit(msg ConsensusMessage) {
+	p.addVote(p.commitVotes, msg)
+	p.checkCommitted(slotOf(msg))
+}
+
+// addVote records the vote of a node in a slot.
+func (p *PBFTPoA) addVote(votes map[slot]map[string]ConsensusMessage, msg ConsensusMessage) {
+	if msg.Block == nil || msg.View < p.currentView || blockSequence(msg.Block) != msg.Sequence {
 		return
 	}
-	// Validate block matches pre-prepare
-	if hex.EncodeToString(types.HashBlock(p.prePrepareMsgs[msg.View])) != hex.EncodeToString(types.HashBlock(msg.Block)) {
+	s := slotOf(msg)
+	if votes[s] == nil {
+		votes[s] = make(map[string]ConsensusMessage)
+	}
+	if prev, ok := votes[s][msg.NodeID]; ok {
+		// the first vote counts, a different second one is equivocation
+		p.checkConflictingVote(prev, msg)
 		return
 	}
+	votes[s][msg.NodeID] = msg
+}
 
-	if p.prepareVotes[msg.View] == nil {
-		p.prepareVotes[msg.View] = make(map[string]bool)
+// matchingVotes returns the votes for the pre-prepared block of a slot.
+func (p *PBFTPoA) matchingVotes(votes map[slot]map[string]ConsensusMessage, s slot) []ConsensusMessage {
+	block := p.prePrepareMsgs[s]
+	if block == nil {
+		return nil
 	}
-	p.prepareVotes[msg.View][msg.NodeID] = true
-
-	// Check if we have quorum
-	if len(p.prepareVotes[msg.View]) >= p.quorumSize {
-		// Broadcast Commit
-		if p.commitVotes[msg.View] == nil {
-			p.commitVotes[msg.View] = make(map[string]bool)
-		}
-		p.commitVotes[msg.View][p.nodeID] = true
-		commitMsg := ConsensusMessage{
-			Type:   "Commit",
-			Block:  p.prePrepareMsgs[msg.View],
-			View:   msg.View,
-			NodeID: p.nodeID,
+	hash := types.HashBlock(block)
+	matching := []ConsensusMessage{}
+	for _, vote := range votes[s] {
+		if bytes.Equal(types.HashBlock(vote.Block), hash) {
+			matching = append(matching, vote)
 		}
-		p.broadcast(commitMsg)
 	}
+	return matching
 }
 
-func (p *PBFTPoA) handleCommit(msg ConsensusMessage) {
-	// Validate block
-	if p.prePrepareMsgs[msg.View] == nil {
+func (p *PBFTPoA) checkPrepared(s slot) {
+	// Check if we have quorum
+	votes := p.matchingVotes(p.prepareVotes, s)
+	if len(votes) < p.quorumSize {
+		return
+	}
+	// Broadcast Commit once per slot
+	if _, voted := p.commitVotes[s][p.nodeID]; voted {
 		return
 	}
-	if hex.EncodeToString(types.HashBlock(p.prePrepareMsgs[msg.View])) != hex.EncodeToString(types.HashBlock(msg.Block)) {
+	p.prepared = &preparedCertificate{View: s.view, Block: p.prePrepareMsgs[s], Prepares: votes}
+	prepared := ConsensusMessage{Type: walPrepared, Block: p.prepared.Block, View: s.view, Sequence: s.sequence, Proof: votes}
+	if err := p.logEntry(prepared); err != nil {
+		util.Logger.Error().Msgf("failed to log prepared certificate of sequence [%d]: [%s]", s.sequence, err)
 		return
 	}
 
-	if p.commitVotes[msg.View] == nil {
-		p.commitVotes[msg.View] = make(map[string]bool)
+	commitMsg := ConsensusMessage{
+		Type:     "Commit",
+		Block:    p.prePrepareMsgs[s],
+		View:     s.view,
+		Sequence: s.sequence,
+		NodeID:   p.nodeID,
 	}
-	p.commitVotes[msg.View][msg.NodeID] = true
+	p.broadcast(commitMsg)
+}
 
+func (p *PBFTPoA) checkCommitted(s slot) {
 	// Check for quorum of commits
-	if len(p.commitVotes[msg.View]) >= p.quorumSize {
-		// Finalize the block
-		finalizedBlock := p.prePrepareMsgs[msg.View]
-		// Add to blockchain storage
+	votes := p.matchingVotes(p.commitVotes, s)
+	if p.committedSlots[s] || s.sequence <= p.lastCommitted || len(votes) < p.quorumSize {
+		return
+	}
+	p.committedSlots[s] = true
+	p.lastCommitted = s.sequence
+	p.lastBlock = types.HashBlock(p.prePrepareMsgs[s])
+	if err := p.logEntry(ConsensusMessage{Type: walCommitted, View: s.view, Sequence: s.sequence}); err != nil {
+		util.Logger.Error().Msgf("failed to log commit of sequence [%d]: [%s]", s.sequence, err)
+	}
+
+	// Finalize the block
+	finalizedBlock := p.prePrepareMsgs[s]
+	util.Logger.Info().Msgf("finalized block [%s] in view [%d] sequence [%d]", hex.EncodeToString(types.HashBlock(finalizedBlock))[:8], s.view, s.sequence)
+	p.committed <- Commit{
+		Block:       finalizedBlock,
+		View:        s.view,
+		Certificate: p.commitCertificate(finalizedBlock, s.view, votes),
+	}
+	p.applyValidatorSet(finalizedBlock)
+
+	// the in-flight block is done once any block is committed, its transactions
+	// are proposed again by the next leader
+	p.pending = nil
+	p.prepared = nil
+	p.stopViewTimer()
+	p.timeout = viewChangeTimeout
+
+	if s.sequence%p.interval == 0 {
+		p.sendCheckpoint(finalizedBlock)
+	}
+}
 
-		//n.chain.AddBlock(finalizedBlock) <---------------------------------------------
-		util.Logger.Warn().Msgf("Finalized block [%s] in view [%d]", hex.EncodeToString(types.HashBlock(finalizedBlock))[:8], msg.View)
+// commitCertificate collects the commit signatures that finalized a block.
+func (p *PBFTPoA) commitCertificate(b *proto.Block, view int, votes []ConsensusMessage) *proto.CommitCertificate {
+	cert := &proto.CommitCertificate{
+		View:      int64(view),
+		Sequence:  int64(blockSequence(b)),
+		BlockHash: types.HashBlock(b),
+	}
+	for _, vote := range votes {
+		cert.Votes = append(cert.Votes, &proto.CommitVote{
+			PublicKey: p.known[vote.NodeID].Bytes(),
+			Signature: vote.Signature,
+		})
+	}
+	return cert
+}
+
+// enterView moves the engine to a new view and drops the state of older views.
+func (p *PBFTPoA) enterView(view int) {
+	p.currentView = view
+	p.viewChanging = false
+	p.pruneSlots(func(s slot) bool { return s.view < view })
+	for v := range p.viewChanges {
+		if v <= view {
+			delete(p.viewChanges, v)
+		}
+	}
+	for v := range p.newViewSent {
+		if v <= view {
+			delete(p.newViewSent, v)
+		}
+	}
+	util.Logger.Info().Msgf("entered view [%d] with leader [%s]", view, p.leader(view))
+}
 
-		// Move to next view
-		p.currentView++
+// pruneSlots drops the messages and votes of every slot matching the filter.
+func (p *PBFTPoA) pruneSlots(drop func(slot) bool) {
+	for s := range p.prePrepareMsgs {
+		if drop(s) {
+			delete(p.prePrepareMsgs, s)
+		}
+	}
+	for s := range p.prepareVotes {
+		if drop(s) {
+			delete(p.prepareVotes, s)
+		}
+	}
+	for s := range p.commitVotes {
+		if drop(s) {
+			delete(p.commitVotes, s)
+		}
+	}
+	for s := range p.committedSlots {
+		if drop(s) {
+			delete(p.committedSlots, s)
+		}
 	}
 }
 
+// broadcast signs a message, sends it to all peers and queues it for this node
+// as well, so the node's own votes are counted.
 func (p *PBFTPoA) broadcast(msg ConsensusMessage) {
-	// This depends on how the existing P2P layer is implemented.
-	// For example:
-	// node.BroadcastConsensusMessage(msg)
+	// nodes outside of the validator set only follow the votes of others
+	if !p.isValidator {
+		return
+	}
+	msg.Sign(p.privKey)
+	// a vote is durable before anyone sees it, a restarted node never casts a different one
+	if err := p.logEntry(msg); err != nil {
+		util.Logger.Error().Msgf("failed to log [%s] message in view [%d], not sending it: [%s]", msg.Type, msg.View, err)
+		return
+	}
+	if p.transport != nil {
+		if err := p.transport.BroadcastConsensusMessage(msg); err != nil {
+			util.Logger.Error().Msgf("failed to broadcast [%s] message in view [%d]: [%s]", msg.Type, msg.View, err)
+		}
+	}
+	p.selfMsgs = append(p.selfMsgs, msg)
 }
 
+// ValidateBlock checks a proposed block before the node votes for it: the
+// proposer signature, the link to the last committed block, governance and
+// evidence, and the chain rules of the node.
 func (p *PBFTPoA) ValidateBlock(b *proto.Block) bool {
-	// Implement block validation logic, similar to what the chain currently does
-	// e.g. validate transactions, previous hash, etc.
+	hash := hex.EncodeToString(types.HashBlock(b))[:8]
+	if !types.VerifyBlock(b) {
+		util.Logger.Error().Msgf("invalid proposer signature on block [%s]", hash)
+		return false
+	}
+	// the engine knows the hash of the block it committed last, unless it
+	// restarted since
+	linked := p.lastBlock != nil && blockSequence(b) == p.lastCommitted+1
+	if linked && !bytes.Equal(b.Header.PrevHash, p.lastBlock) {
+		util.Logger.Error().Msgf("block [%s] does not extend the last committed block", hash)
+		return false
+	}
+	// evidence and governance decide who stays in the validator set, a leader cannot make them up
+	if err := types.VerifyBlockGovernance(p.validatorSet, b); err != nil {
+		util.Logger.Error().Msgf("invalid governance in block [%s]: [%s]", hash, err)
+		return false
+	}
+	if !types.VerifyBlockEvidence(b) {
+		return false
+	}
+	if p.blockRules == nil {
+		return true
+	}
+	// the node applies committed blocks after the engine commits them, a block
+	// on top of the last committed one may arrive before its parent is applied
+	if err := p.blockRules(b); err != nil && !(linked && errors.Is(err, ErrUnknownParent)) {
+		util.Logger.Error().Msgf("block [%s] breaks the chain rules: [%s]", hash, err)
+		return false
+	}
 	return true
 }
 
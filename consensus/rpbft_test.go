@@ -0,0 +1,402 @@
+package consensus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryNetwork delivers consensus messages between engines of one process.
+type memoryNetwork struct {
+	engines map[string]*PBFTPoA
+	down    map[string]bool
+}
+
+type memoryTransport struct {
+	network *memoryNetwork
+	nodeID  string
+}
+
+func (t *memoryTransport) BroadcastConsensusMessage(msg ConsensusMessage) error {
+	for id, engine := range t.network.engines {
+		if id == t.nodeID || t.network.down[id] {
+			continue
+		}
+		go engine.OnReceiveMessage(msg)
+	}
+	return nil
+}
+
+func newTestNetwork(t *testing.T, n int) (*memoryNetwork, []*crypto.PrivateKey) {
+	var (
+		network    = &memoryNetwork{engines: make(map[string]*PBFTPoA), down: make(map[string]bool)}
+		keys       = make([]*crypto.PrivateKey, n)
+		validators = make([]*crypto.PublicKey, n)
+	)
+	for i := range keys {
+		keys[i] = crypto.GeneratePrivateKey()
+		validators[i] = keys[i].Public()
+	}
+	for _, key := range keys {
+		id := key.Public().String()
+		engine := NewPBFTPoA(validators, key, &memoryTransport{network: network, nodeID: id}, nil)
+		engine.timeout = 200 * time.Millisecond
+		network.engines[id] = engine
+	}
+	t.Cleanup(func() {
+		for id, engine := range network.engines {
+			if !network.down[id] {
+				engine.Stop()
+			}
+		}
+	})
+	return network, keys
+}
+
+func signedBlock(privKey *crypto.PrivateKey) *proto.Block {
+	b := util.RandomBlock()
+	b.Header.Height = 1
+	types.SignBlock(privKey, b)
+	return b
+}
+
+// nextBlock returns a signed block on top of prev, a nil prev starts the chain.
+func nextBlock(t *testing.T, privKey *crypto.PrivateKey, prev *proto.Block, txx ...*proto.Transaction) *proto.Block {
+	b := util.RandomBlock()
+	b.Header.Height = 1
+	if prev != nil {
+		b.Header.Height = prev.Header.Height + 1
+		b.Header.PrevHash = types.HashBlock(prev)
+	}
+	b.Transactions = txx
+	if len(txx) > 0 {
+		tree, err := types.GetMerkleTree(b)
+		require.Nil(t, err)
+		b.Header.RootHash = tree.MerkleRoot()
+	}
+	types.SignBlock(privKey, b)
+	return b
+}
+
+func waitCommit(t *testing.T, engine Consensus) Commit {
+	select {
+	case c := <-engine.Committed():
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("block was not committed")
+	}
+	return Commit{}
+}
+
+func TestSingleValidatorCommits(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	engine := NewPBFTPoA(nil, privKey, nil, nil)
+	engine.Start()
+	defer engine.Stop()
+
+	block := signedBlock(privKey)
+	require.Nil(t, engine.ProposeBlock(block))
+
+	c := waitCommit(t, engine)
+	assert.Equal(t, types.HashBlock(block), types.HashBlock(c.Block))
+	assert.Equal(t, 0, c.View)
+}
+
+func TestFourValidatorsCommit(t *testing.T) {
+	network, keys := newTestNetwork(t, 4)
+	for _, engine := range network.engines {
+		engine.Start()
+	}
+
+	leader := network.engines[keys[0].Public().String()]
+	block := signedBlock(keys[0])
+	require.Nil(t, leader.ProposeBlock(block))
+
+	validators := make([]*crypto.PublicKey, len(keys))
+	for i, key := range keys {
+		validators[i] = key.Public()
+	}
+	for _, engine := range network.engines {
+		c := waitCommit(t, engine)
+		assert.Equal(t, types.HashBlock(block), types.HashBlock(c.Block))
+		assert.Equal(t, types.HashBlock(block), c.Certificate.BlockHash)
+		assert.True(t, types.VerifyCommitCertificate(c.Certificate, validators))
+	}
+}
+
+func TestUnknownValidatorIsIgnored(t *testing.T) {
+	var (
+		privKey  = crypto.GeneratePrivateKey()
+		outsider = crypto.GeneratePrivateKey()
+		engine   = NewPBFTPoA([]*crypto.PublicKey{privKey.Public(), crypto.GeneratePrivateKey().Public()}, privKey, nil, nil)
+		block    = signedBlock(outsider)
+	)
+
+	engine.handleMessage(ConsensusMessage{Type: "PrePrepare", Block: block, View: 0, Sequence: blockSequence(block), NodeID: outsider.Public().String()})
+	assert.Empty(t, engine.prePrepareMsgs)
+}
+
+func TestReceiveNeverBlocks(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		engine  = NewPBFTPoA([]*crypto.PublicKey{privKey.Public(), crypto.GeneratePrivateKey().Public()}, privKey, nil, nil)
+		msg     = ConsensusMessage{Type: "Prepare", NodeID: privKey.Public().String()}
+	)
+
+	// messages before the start are dropped
+	engine.OnReceiveMessage(msg)
+	assert.Empty(t, engine.incomingMsgs)
+
+	// a full queue drops the message instead of holding up the sender, the
+	// run loop is not running to empty it
+	engine.started.Store(true)
+	done := make(chan struct{})
+	go func() {
+		for range cap(engine.incomingMsgs) + 1 {
+			engine.OnReceiveMessage(msg)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("receiving a message blocked")
+	}
+	assert.Len(t, engine.incomingMsgs, cap(engine.incomingMsgs))
+}
+
+func TestViewChangeRecoversFromCrashedLeader(t *testing.T) {
+	network, keys := newTestNetwork(t, 4)
+
+	// the leader of view 0 never comes up
+	crashed := keys[0].Public().String()
+	network.down[crashed] = true
+	for id, engine := range network.engines {
+		if id != crashed {
+			engine.Start()
+		}
+	}
+
+	block := signedBlock(keys[1])
+	for id, engine := range network.engines {
+		if id != crashed {
+			require.Nil(t, engine.ProposeBlock(block))
+		}
+	}
+
+	for id, engine := range network.engines {
+		if id == crashed {
+			continue
+		}
+		c := waitCommit(t, engine)
+		assert.Equal(t, 1, c.View)
+		assert.Equal(t, types.HashBlock(block), types.HashBlock(c.Block))
+	}
+}
+
+func TestViewChangeWithDivergentTimeouts(t *testing.T) {
+	network, keys := newTestNetwork(t, 4)
+
+	// the leader of view 0 never comes up, the others time out at different
+	// times, the leader of view 1 not at all
+	crashed := keys[0].Public().String()
+	network.down[crashed] = true
+	network.engines[keys[1].Public().String()].timeout = time.Minute
+	network.engines[keys[2].Public().String()].timeout = 100 * time.Millisecond
+	network.engines[keys[3].Public().String()].timeout = 700 * time.Millisecond
+	for id, engine := range network.engines {
+		if id != crashed {
+			engine.Start()
+		}
+	}
+
+	block := signedBlock(keys[1])
+	for id, engine := range network.engines {
+		if id != crashed {
+			require.Nil(t, engine.ProposeBlock(block))
+		}
+	}
+
+	// the validators meet in view 1 although the fastest one moved past it
+	for id, engine := range network.engines {
+		if id == crashed {
+			continue
+		}
+		c := waitCommit(t, engine)
+		assert.Equal(t, 1, c.View)
+		assert.Equal(t, types.HashBlock(block), types.HashBlock(c.Block))
+	}
+}
+
+func TestForgedVoteIsRejected(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		other   = crypto.GeneratePrivateKey()
+		engine  = NewPBFTPoA([]*crypto.PublicKey{privKey.Public(), other.Public()}, privKey, nil, nil)
+		block   = signedBlock(privKey)
+	)
+
+	// a prepare claiming to come from the other validator, signed with the wrong key
+	forged := ConsensusMessage{Type: "Prepare", Block: block, View: 0, Sequence: blockSequence(block), NodeID: other.Public().String()}
+	forged.Sign(privKey)
+	engine.handleMessage(forged)
+	assert.Empty(t, engine.prepareVotes[slotOf(forged)])
+
+	// the same vote signed by the other validator is counted
+	vote := ConsensusMessage{Type: "Prepare", Block: block, View: 0, Sequence: blockSequence(block), NodeID: other.Public().String()}
+	vote.Sign(other)
+	engine.handleMessage(vote)
+	assert.Len(t, engine.prepareVotes[slotOf(vote)], 1)
+
+	// tampering with a signed vote invalidates it
+	vote.View = 1
+	engine.handleMessage(vote)
+	assert.Empty(t, engine.prepareVotes[slotOf(vote)])
+}
+
+func TestSequencesCommitInOneView(t *testing.T) {
+	network, keys := newTestNetwork(t, 4)
+	for _, engine := range network.engines {
+		engine.Start()
+	}
+	leader := network.engines[keys[0].Public().String()]
+
+	var block *proto.Block
+	for height := int32(1); height <= 3; height++ {
+		block = nextBlock(t, keys[0], block)
+		require.Nil(t, leader.ProposeBlock(block))
+
+		for _, engine := range network.engines {
+			c := waitCommit(t, engine)
+			assert.Equal(t, 0, c.View)
+			assert.Equal(t, int64(height), c.Certificate.Sequence)
+		}
+	}
+
+	// a height that is already committed is refused
+	stale := signedBlock(keys[0])
+	stale.Header.Height = 2
+	assert.ErrorIs(t, leader.ProposeBlock(stale), ErrStaleSequence)
+}
+
+func TestStableCheckpointPrunesMessages(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	engine := NewPBFTPoA(nil, privKey, nil, nil)
+	engine.interval = 2
+
+	var block *proto.Block
+	for height := int32(1); height <= 3; height++ {
+		block = nextBlock(t, privKey, block)
+		require.Nil(t, engine.handleProposal(block))
+		<-engine.Committed()
+	}
+
+	// the checkpoint at sequence 2 is stable, everything at or below it is gone
+	assert.Equal(t, 2, engine.stableCheckpoint)
+	assert.Empty(t, engine.checkpoints)
+	for s := range engine.prePrepareMsgs {
+		assert.Greater(t, s.sequence, 2)
+	}
+	assert.Len(t, engine.prePrepareMsgs, 1)
+
+	// messages below the low watermark are dropped
+	old := util.RandomBlock()
+	old.Header.Height = 2
+	types.SignBlock(privKey, old)
+	vote := ConsensusMessage{Type: "Prepare", Block: old, View: 0, Sequence: 2, NodeID: privKey.Public().String()}
+	vote.Sign(privKey)
+	engine.handleMessage(vote)
+	assert.Empty(t, engine.prepareVotes[slotOf(vote)])
+
+	// as are messages above the high watermark
+	assert.False(t, engine.inWindow(2+engine.window+1))
+	assert.True(t, engine.inWindow(2+engine.window))
+}
+
+func TestConflictingVoteProducesEvidence(t *testing.T) {
+	var (
+		keys       = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		validators = []*crypto.PublicKey{keys[0].Public(), keys[1].Public(), keys[2].Public(), keys[3].Public()}
+		engine     = NewPBFTPoA(validators, keys[0], nil, nil)
+		accused    = keys[1]
+	)
+
+	// the same validator prepares two different blocks in one slot
+	for _, block := range []*proto.Block{signedBlock(keys[0]), signedBlock(keys[0])} {
+		vote := ConsensusMessage{Type: "Prepare", Block: block, View: 0, Sequence: 1, NodeID: accused.Public().String()}
+		vote.Sign(accused)
+		engine.handleMessage(vote)
+	}
+
+	var ev *proto.Evidence
+	select {
+	case ev = <-engine.Evidence():
+	default:
+		t.Fatal("no evidence reported")
+	}
+	assert.True(t, types.VerifyEvidence(ev))
+	assert.Equal(t, accused.Public().Bytes(), ev.PublicKey)
+
+	// once a block carrying the evidence commits, the validator is out of the set
+	block := signedBlock(keys[0])
+	block.Evidence = []*proto.Evidence{ev}
+	engine.applyValidatorSet(block)
+	assert.Len(t, engine.validators, 3)
+	assert.Nil(t, engine.known[accused.Public().String()])
+	assert.Equal(t, types.QuorumSize(3), engine.quorumSize)
+
+	// its votes are no longer accepted
+	vote := ConsensusMessage{Type: "Prepare", Block: signedBlock(keys[0]), View: 0, Sequence: 1, NodeID: accused.Public().String()}
+	vote.Sign(accused)
+	engine.handleMessage(vote)
+	for _, byNode := range engine.prepareVotes {
+		assert.NotContains(t, byNode, accused.Public().String())
+	}
+}
+
+func TestInvalidProposalIsNotPrepared(t *testing.T) {
+	var (
+		leader  = crypto.GeneratePrivateKey()
+		privKey = crypto.GeneratePrivateKey()
+		engine  = NewPBFTPoA([]*crypto.PublicKey{leader.Public(), privKey.Public()}, privKey, nil, nil)
+		applied = map[string]bool{}
+	)
+	engine.SetBlockValidator(func(b *proto.Block) error {
+		if !applied[hex.EncodeToString(b.Header.PrevHash)] {
+			return fmt.Errorf("%w: height [%d]", ErrUnknownParent, b.Header.Height)
+		}
+		return nil
+	})
+	prePrepare := func(b *proto.Block) bool {
+		msg := ConsensusMessage{Type: "PrePrepare", Block: b, View: 0, Sequence: blockSequence(b), NodeID: leader.Public().String()}
+		msg.Sign(leader)
+		engine.handleMessage(msg)
+		return engine.prePrepareMsgs[slotOf(msg)] != nil
+	}
+
+	// the proposer signature has to cover the block
+	tampered := nextBlock(t, leader, nil)
+	tampered.Header.Timestamp++
+	assert.False(t, prePrepare(tampered))
+
+	// the chain rules of the node reject a block on an unknown parent
+	first := nextBlock(t, leader, nil)
+	assert.False(t, prePrepare(first))
+	applied[hex.EncodeToString(first.Header.PrevHash)] = true
+	assert.True(t, prePrepare(first))
+	assert.Contains(t, engine.prepareVotes[slot{sequence: 1}], privKey.Public().String())
+
+	// once a sequence commits, the next block has to extend it, even before
+	// the node applied it
+	engine.lastCommitted, engine.lastBlock = 1, types.HashBlock(first)
+	assert.False(t, prePrepare(nextBlock(t, leader, signedBlock(leader))))
+	assert.True(t, prePrepare(nextBlock(t, leader, first)))
+}
@@ -0,0 +1,83 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+)
+
+// Solo is a single authority engine for development. Blocks of the authority
+// are final as soon as they are proposed, no messages are exchanged.
+type Solo struct {
+	mu            sync.Mutex
+	authority     *crypto.PublicKey
+	privKey       *crypto.PrivateKey
+	lastCommitted int
+	committed     chan Commit
+}
+
+// NewSolo creates a solo engine. The first validator is the authority, without
+// validators the node itself is.
+func NewSolo(validators []*crypto.PublicKey, privKey *crypto.PrivateKey) *Solo {
+	authority := privKey.Public()
+	if len(validators) > 0 {
+		authority = validators[0]
+	}
+	return &Solo{
+		authority: authority,
+		privKey:   privKey,
+		committed: make(chan Commit, 16),
+	}
+}
+
+func (s *Solo) Start() {
+	util.Logger.Info().Msgf("starting solo consensus with authority [%s]", s.authority)
+}
+
+func (s *Solo) Stop() {}
+
+// ProposeBlock finalizes a block of the authority. The commit certificate holds
+// the single vote of the authority and verifies against a validator set of one.
+func (s *Solo) ProposeBlock(b *proto.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !bytes.Equal(s.privKey.Public().Bytes(), s.authority.Bytes()) {
+		return ErrNotProposer
+	}
+	if blockSequence(b) <= s.lastCommitted {
+		return ErrStaleSequence
+	}
+	s.lastCommitted = blockSequence(b)
+
+	vote := ConsensusMessage{Type: "Commit", Block: b, Sequence: blockSequence(b), NodeID: s.authority.String()}
+	vote.Sign(s.privKey)
+
+	util.Logger.Info().Msgf("finalized block [%s] sequence [%d]", hex.EncodeToString(types.HashBlock(b))[:8], vote.Sequence)
+	s.committed <- Commit{
+		Block: b,
+		Certificate: &proto.CommitCertificate{
+			Sequence:  int64(vote.Sequence),
+			BlockHash: types.HashBlock(b),
+			Votes:     []*proto.CommitVote{{PublicKey: s.authority.Bytes(), Signature: vote.Signature}},
+		},
+	}
+	return nil
+}
+
+// ValidateBlock accepts blocks signed by the authority.
+func (s *Solo) ValidateBlock(b *proto.Block) bool {
+	return bytes.Equal(b.PublicKey, s.authority.Bytes())
+}
+
+// OnReceiveMessage ignores consensus messages, the authority never waits for votes.
+func (s *Solo) OnReceiveMessage(msg ConsensusMessage) {}
+
+func (s *Solo) Committed() <-chan Commit {
+	return s.committed
+}
@@ -0,0 +1,27 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoloCommitsAuthorityBlocks(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	engine := NewSolo(nil, privKey)
+
+	block := signedBlock(privKey)
+	require.Nil(t, engine.ProposeBlock(block))
+	c := waitCommit(t, engine)
+	assert.Equal(t, types.HashBlock(block), types.HashBlock(c.Block))
+	assert.True(t, types.VerifyCommitCertificate(c.Certificate, []*crypto.PublicKey{privKey.Public()}))
+	assert.ErrorIs(t, engine.ProposeBlock(block), ErrStaleSequence)
+
+	// another node configured with the same authority does not produce blocks
+	follower := NewSolo([]*crypto.PublicKey{privKey.Public()}, crypto.GeneratePrivateKey())
+	assert.ErrorIs(t, follower.ProposeBlock(signedBlock(privKey)), ErrNotProposer)
+	assert.True(t, follower.ValidateBlock(block))
+}
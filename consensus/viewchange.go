@@ -0,0 +1,215 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+)
+
+// preparedCertificate proves that a block gathered a quorum of prepare votes in a view.
+type preparedCertificate struct {
+	View     int
+	Block    *proto.Block
+	Prepares []ConsensusMessage
+}
+
+// startViewTimer arms the view change timer unless it is already running.
+func (p *PBFTPoA) startViewTimer() {
+	if p.timerRunning {
+		return
+	}
+	p.timerRunning = true
+	p.viewTimer.Reset(p.timeout)
+}
+
+// stopViewTimer disarms the view change timer.
+func (p *PBFTPoA) stopViewTimer() {
+	if !p.viewTimer.Stop() && p.timerRunning {
+		// drain a timeout that fired but has not been handled yet
+		select {
+		case <-p.viewTimer.C:
+		default:
+		}
+	}
+	p.timerRunning = false
+}
+
+// handleViewTimeout suspects the leader of the current (or the targeted) view
+// and moves on to the next one. The timeout doubles on every failed attempt.
+func (p *PBFTPoA) handleViewTimeout() {
+	p.timerRunning = false
+	if !p.isValidator {
+		return
+	}
+
+	view := p.currentView + 1
+	if p.viewChanging && p.targetView >= view {
+		view = p.targetView + 1
+	}
+	util.Logger.Warn().Msgf("view [%d] timed out after [%s], leader [%s] suspected", p.currentView, p.timeout, p.leader(p.currentView))
+	p.timeout *= 2
+	p.startViewChange(view)
+}
+
+// startViewChange stops taking part in the current view and votes for the
+// given one, carrying the prepared certificate of the in-flight block.
+func (p *PBFTPoA) startViewChange(view int) {
+	p.viewChanging = true
+	p.targetView = view
+
+	msg := ConsensusMessage{
+		Type:   "ViewChange",
+		View:   view,
+		NodeID: p.nodeID,
+	}
+	if p.prepared != nil {
+		msg.Block = p.prepared.Block
+		msg.Sequence = blockSequence(p.prepared.Block)
+		msg.Proof = p.prepared.Prepares
+	}
+
+	p.startViewTimer()
+	p.broadcast(msg)
+}
+
+func (p *PBFTPoA) handleViewChange(msg ConsensusMessage) {
+	if msg.View <= p.currentView {
+		return
+	}
+	if !p.validPreparedProof(msg) {
+		util.Logger.Warn().Msgf("invalid prepared certificate in view change from [%s]", msg.NodeID)
+		return
+	}
+
+	if p.viewChanges[msg.View] == nil {
+		p.viewChanges[msg.View] = make(map[string]ConsensusMessage)
+	}
+	p.viewChanges[msg.View][msg.NodeID] = msg
+
+	// f+1 validators want the view, at least one of them is honest, so this
+	// validator joins them whatever its own timer says. Validators whose
+	// timeouts grew apart meet in the same view again.
+	if p.isValidator && len(p.viewChanges[msg.View]) >= p.faultyLimit()+1 && (!p.viewChanging || p.targetView < msg.View) {
+		p.startViewChange(msg.View)
+	}
+
+	// the leader of the new view announces it once a quorum wants to move on,
+	// whether or not it timed out itself
+	if p.leader(msg.View) != p.nodeID || len(p.viewChanges[msg.View]) < p.quorumSize || p.newViewSent[msg.View] {
+		return
+	}
+	p.newViewSent[msg.View] = true
+
+	proof := make([]ConsensusMessage, 0, len(p.viewChanges[msg.View]))
+	for _, vc := range p.viewChanges[msg.View] {
+		proof = append(proof, vc)
+	}
+
+	// re-propose the highest prepared block, otherwise recover the in-flight one
+	block := highestPrepared(proof)
+	if block == nil {
+		block = p.pending
+	}
+
+	p.broadcast(ConsensusMessage{
+		Type:     "NewView",
+		Block:    block,
+		View:     msg.View,
+		Sequence: blockSequence(block),
+		NodeID:   p.nodeID,
+		Proof:    proof,
+	})
+}
+
+func (p *PBFTPoA) handleNewView(msg ConsensusMessage) {
+	if msg.View < p.currentView || (msg.View == p.currentView && !p.viewChanging) {
+		return
+	}
+	if p.leader(msg.View) != msg.NodeID {
+		return
+	}
+
+	// the new view must be backed by a quorum of valid view changes
+	voters := make(map[string]bool)
+	for _, vc := range msg.Proof {
+		if vc.Type != "ViewChange" || vc.View != msg.View || !vc.Verify(p.known[vc.NodeID]) || !p.validPreparedProof(vc) {
+			return
+		}
+		voters[vc.NodeID] = true
+	}
+	if len(voters) < p.quorumSize {
+		util.Logger.Warn().Msgf("new view [%d] from [%s] without quorum", msg.View, msg.NodeID)
+		return
+	}
+
+	// a block prepared in an earlier view must survive the view change
+	if prepared := highestPrepared(msg.Proof); prepared != nil {
+		if msg.Block == nil || !bytes.Equal(types.HashBlock(prepared), types.HashBlock(msg.Block)) {
+			util.Logger.Warn().Msgf("new view [%d] from [%s] drops a prepared block", msg.View, msg.NodeID)
+			return
+		}
+	}
+
+	p.stopViewTimer()
+	p.enterView(msg.View)
+	p.prepared = nil
+	if msg.Block != nil {
+		util.Logger.Info().Msgf("recovering block [%s] in view [%d]", hex.EncodeToString(types.HashBlock(msg.Block))[:3], msg.View)
+		p.acceptPrePrepare(slot{view: msg.View, sequence: blockSequence(msg.Block)}, msg.Block)
+	}
+}
+
+// faultyLimit returns the number of faulty validators the set tolerates.
+func (p *PBFTPoA) faultyLimit() int {
+	return (len(p.validators) - 1) / 3
+}
+
+// validPreparedProof checks the prepared certificate carried by a view change.
+func (p *PBFTPoA) validPreparedProof(msg ConsensusMessage) bool {
+	if msg.Block == nil {
+		return len(msg.Proof) == 0
+	}
+	if len(msg.Proof) == 0 {
+		return false
+	}
+
+	hash := types.HashBlock(msg.Block)
+	view := msg.Proof[0].View
+	voters := make(map[string]bool)
+	for _, prepare := range msg.Proof {
+		if prepare.Type != "Prepare" || prepare.View != view || prepare.Block == nil {
+			return false
+		}
+		if !prepare.Verify(p.known[prepare.NodeID]) {
+			return false
+		}
+		if !bytes.Equal(types.HashBlock(prepare.Block), hash) {
+			return false
+		}
+		voters[prepare.NodeID] = true
+	}
+
+	return len(voters) >= p.quorumSize
+}
+
+// highestPrepared returns the block prepared in the highest view among view
+// change messages, or nil when none of them carries a prepared certificate.
+func highestPrepared(viewChanges []ConsensusMessage) *proto.Block {
+	var (
+		block *proto.Block
+		view  = -1
+	)
+	for _, vc := range viewChanges {
+		if vc.Block == nil || len(vc.Proof) == 0 {
+			continue
+		}
+		if vc.Proof[0].View > view {
+			view = vc.Proof[0].View
+			block = vc.Block
+		}
+	}
+	return block
+}
@@ -0,0 +1,279 @@
+package consensus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Entries of the write-ahead log are consensus messages. Next to the messages a
+// validator signs, the log keeps records that never go over the wire:
+//
+//	Prepared   - the prepared certificate of a slot, Proof holds the prepare votes
+//	Committed  - a slot that reached the commit quorum
+//	Stable     - the stable checkpoint, the first record of a compacted log
+//	Validators - the validator set after a committed block changed it, see governance.go
+const (
+	walPrepared  = "Prepared"
+	walCommitted = "Committed"
+	walStable    = "Stable"
+)
+
+// walHeaderLen is the size of the length and checksum prefix of every record.
+const walHeaderLen = 8
+
+// WAL persists the consensus state a validator must not forget across a
+// restart, the votes it sent in particular, so it never votes twice in a slot.
+type WAL interface {
+	Write(msg ConsensusMessage) error
+	Replay() ([]ConsensusMessage, error)
+	Rewrite(entries []ConsensusMessage) error
+	Close() error
+}
+
+// FileWAL is an append-only WAL file. Every record is written and synced
+// before the message it describes is sent.
+type FileWAL struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// OpenFileWAL opens the WAL at path, creating it when it does not exist.
+func OpenFileWAL(path string) (*FileWAL, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0774); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0664)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWAL{path: path, file: file}, nil
+}
+
+func (w *FileWAL) Write(msg ConsensusMessage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	record, err := encodeWALRecord(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := w.file.Write(record); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Replay returns all entries in the order they were written. A record torn by
+// a crash in the middle of a write ends the log.
+func (w *FileWAL) Replay() ([]ConsensusMessage, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.readAll()
+}
+
+// Rewrite replaces the log with the given entries. The log is rewritten next to
+// the old one and swapped in atomically, a crash leaves one or the other.
+func (w *FileWAL) Rewrite(entries []ConsensusMessage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.rewrite(entries)
+}
+
+func (w *FileWAL) rewrite(entries []ConsensusMessage) error {
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0664)
+	if err != nil {
+		return err
+	}
+	for _, msg := range entries {
+		record, err := encodeWALRecord(msg)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err := tmp.Write(record); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, w.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0664)
+	if err != nil {
+		return err
+	}
+	w.file.Close()
+	w.file = file
+	return nil
+}
+
+func (w *FileWAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+func (w *FileWAL) readAll() ([]ConsensusMessage, error) {
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var (
+		entries = []ConsensusMessage{}
+		reader  = bufio.NewReader(w.file)
+		header  = make([]byte, walHeaderLen)
+	)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return entries, nil
+			}
+			return nil, err
+		}
+		data := make([]byte, binary.BigEndian.Uint32(header[:4]))
+		if _, err := io.ReadFull(reader, data); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				util.Logger.Warn().Msgf("consensus wal [%s] ends with a torn record", w.path)
+				return entries, nil
+			}
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) {
+			util.Logger.Warn().Msgf("consensus wal [%s] ends with a corrupted record", w.path)
+			return entries, nil
+		}
+
+		msg := &proto.ConsensusMessage{}
+		if err := pb.Unmarshal(data, msg); err != nil {
+			return nil, err
+		}
+		entries = append(entries, MessageFromProto(msg))
+	}
+}
+
+func encodeWALRecord(msg ConsensusMessage) ([]byte, error) {
+	data, err := pb.Marshal(msg.ToProto())
+	if err != nil {
+		return nil, err
+	}
+	record := make([]byte, walHeaderLen+len(data))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:walHeaderLen], crc32.ChecksumIEEE(data))
+	copy(record[walHeaderLen:], data)
+	return record, nil
+}
+
+// logEntry writes an entry to the WAL, engines without a WAL keep no log.
+func (p *PBFTPoA) logEntry(msg ConsensusMessage) error {
+	if p.wal == nil {
+		return nil
+	}
+	return p.wal.Write(msg)
+}
+
+// replay restores the state recorded in the WAL before the engine takes part
+// in consensus again. The own votes of a slot are restored together with the
+// block they were cast for, so a conflicting block in that slot is ignored.
+func (p *PBFTPoA) replay() {
+	if p.wal == nil {
+		return
+	}
+	entries, err := p.wal.Replay()
+	if err != nil {
+		util.Logger.Error().Msgf("failed to replay consensus wal: [%s]", err)
+		return
+	}
+
+	for _, msg := range entries {
+		s := slotOf(msg)
+		switch msg.Type {
+		case "PrePrepare":
+			p.prePrepareMsgs[s] = msg.Block
+			p.currentView = max(p.currentView, msg.View)
+		case "Prepare":
+			if p.prePrepareMsgs[s] == nil {
+				p.prePrepareMsgs[s] = msg.Block
+			}
+			p.addVote(p.prepareVotes, msg)
+			p.currentView = max(p.currentView, msg.View)
+		case "Commit":
+			p.addVote(p.commitVotes, msg)
+			p.currentView = max(p.currentView, msg.View)
+		case walPrepared:
+			if p.prepared == nil || msg.View >= p.prepared.View {
+				p.prepared = &preparedCertificate{View: msg.View, Block: msg.Block, Prepares: msg.Proof}
+			}
+		case walCommitted:
+			p.committedSlots[s] = true
+			p.lastCommitted = max(p.lastCommitted, msg.Sequence)
+		case "Checkpoint":
+			if p.checkpoints[msg.Sequence] == nil {
+				p.checkpoints[msg.Sequence] = make(map[string]ConsensusMessage)
+			}
+			p.checkpoints[msg.Sequence][msg.NodeID] = msg
+		case walValidators:
+			if msg.ValidatorSet.GetHeight() >= p.validatorSet.GetHeight() {
+				p.useValidatorSet(msg.ValidatorSet)
+			}
+		case walStable:
+			p.stableCheckpoint = max(p.stableCheckpoint, msg.Sequence)
+			p.lastCommitted = max(p.lastCommitted, msg.Sequence)
+			p.currentView = max(p.currentView, msg.View)
+		case "ViewChange":
+			if p.viewChanges[msg.View] == nil {
+				p.viewChanges[msg.View] = make(map[string]ConsensusMessage)
+			}
+			p.viewChanges[msg.View][msg.NodeID] = msg
+			p.targetView = max(p.targetView, msg.View)
+		case "NewView":
+			p.newViewSent[msg.View] = true
+			p.currentView = max(p.currentView, msg.View)
+		}
+	}
+
+	for s := range p.committedSlots {
+		if s.sequence == p.lastCommitted && p.prePrepareMsgs[s] != nil {
+			p.lastBlock = types.HashBlock(p.prePrepareMsgs[s])
+		}
+	}
+
+	p.viewChanging = p.targetView > p.currentView
+	p.pruneSlots(func(s slot) bool { return s.view < p.currentView || s.sequence <= p.stableCheckpoint })
+	if p.prepared != nil && blockSequence(p.prepared.Block) <= p.lastCommitted {
+		p.prepared = nil
+	}
+
+	// a view change or a block in flight was interrupted, the leader has to make progress
+	inFlight := p.viewChanging
+	for s := range p.prePrepareMsgs {
+		inFlight = inFlight || !p.committedSlots[s]
+	}
+	if inFlight {
+		p.startViewTimer()
+	}
+	util.Logger.Info().Msgf("replayed [%d] consensus wal entries, view [%d] last committed [%d]", len(entries), p.currentView, p.lastCommitted)
+}
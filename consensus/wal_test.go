@@ -0,0 +1,159 @@
+package consensus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWALReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "consensus.wal")
+	wal, err := OpenFileWAL(path)
+	require.Nil(t, err)
+
+	privKey := crypto.GeneratePrivateKey()
+	for seq := 1; seq <= 3; seq++ {
+		msg := ConsensusMessage{Type: "Prepare", Block: signedBlock(privKey), View: 0, Sequence: seq, NodeID: privKey.Public().String()}
+		msg.Sign(privKey)
+		require.Nil(t, wal.Write(msg))
+	}
+	require.Nil(t, wal.Close())
+
+	// a record torn by a crash is dropped, the ones before it survive
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0664)
+	require.Nil(t, err)
+	_, err = file.Write([]byte{0, 0, 1, 0, 1, 2})
+	require.Nil(t, err)
+	require.Nil(t, file.Close())
+
+	wal, err = OpenFileWAL(path)
+	require.Nil(t, err)
+	defer wal.Close()
+
+	entries, err := wal.Replay()
+	require.Nil(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, 2, entries[1].Sequence)
+	assert.True(t, entries[1].Verify(privKey.Public()))
+
+	require.Nil(t, wal.Rewrite([]ConsensusMessage{{Type: walStable, Sequence: 2}, entries[2]}))
+	entries, err = wal.Replay()
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, walStable, entries[0].Type)
+	assert.Equal(t, 3, entries[1].Sequence)
+}
+
+func TestRestartedValidatorDoesNotEquivocate(t *testing.T) {
+	var (
+		leader     = crypto.GeneratePrivateKey()
+		privKey    = crypto.GeneratePrivateKey()
+		validators = []*crypto.PublicKey{leader.Public(), privKey.Public()}
+		path       = filepath.Join(t.TempDir(), "consensus.wal")
+	)
+	prePrepare := func(engine *PBFTPoA) ConsensusMessage {
+		block := signedBlock(leader)
+		msg := ConsensusMessage{Type: "PrePrepare", Block: block, View: 0, Sequence: blockSequence(block), NodeID: leader.Public().String()}
+		msg.Sign(leader)
+		engine.handleMessage(msg)
+		return msg
+	}
+
+	wal, err := OpenFileWAL(path)
+	require.Nil(t, err)
+	engine := NewPBFTPoA(validators, privKey, nil, wal)
+	first := prePrepare(engine)
+	require.Nil(t, wal.Close())
+
+	// the validator restarts and the leader proposes another block in the same slot
+	wal, err = OpenFileWAL(path)
+	require.Nil(t, err)
+	defer wal.Close()
+	engine = NewPBFTPoA(validators, privKey, nil, wal)
+	engine.replay()
+	engine.stopViewTimer()
+	prePrepare(engine)
+
+	s := slotOf(first)
+	require.NotNil(t, engine.prePrepareMsgs[s])
+	assert.Equal(t, types.HashBlock(first.Block), types.HashBlock(engine.prePrepareMsgs[s]))
+	vote := engine.prepareVotes[s][privKey.Public().String()]
+	assert.Equal(t, types.HashBlock(first.Block), types.HashBlock(vote.Block))
+}
+
+// crashingWAL stops persisting after a number of writes, as if the process
+// died, the file keeps what was written before.
+type crashingWAL struct {
+	WAL
+	writes int
+}
+
+var errCrashed = errors.New("crashed")
+
+func (w *crashingWAL) Write(msg ConsensusMessage) error {
+	if w.writes == 0 {
+		return errCrashed
+	}
+	w.writes--
+	return w.WAL.Write(msg)
+}
+
+func (w *crashingWAL) Rewrite(entries []ConsensusMessage) error {
+	if w.writes == 0 {
+		return errCrashed
+	}
+	w.writes--
+	return w.WAL.Rewrite(entries)
+}
+
+func TestStableCheckpointSurvivesCrash(t *testing.T) {
+	var (
+		leader     = crypto.GeneratePrivateKey()
+		privKey    = crypto.GeneratePrivateKey()
+		validators = []*crypto.PublicKey{leader.Public(), privKey.Public()}
+	)
+
+	for writes := 0; writes <= 1; writes++ {
+		path := filepath.Join(t.TempDir(), "consensus.wal")
+		wal, err := OpenFileWAL(path)
+		require.Nil(t, err)
+
+		// votes up to sequence 3 and a view change without a sequence
+		engine := NewPBFTPoA(validators, privKey, nil, wal)
+		for seq := 1; seq <= 3; seq++ {
+			require.Nil(t, engine.logEntry(ConsensusMessage{Type: "Prepare", Block: signedBlock(leader), Sequence: seq, NodeID: engine.nodeID}))
+			require.Nil(t, engine.logEntry(ConsensusMessage{Type: walCommitted, Sequence: seq}))
+		}
+		require.Nil(t, engine.logEntry(ConsensusMessage{Type: "ViewChange", View: 1, NodeID: engine.nodeID}))
+		engine.validatorSet.Height = 3
+
+		// the process dies after the given number of writes to the log
+		engine.wal = &crashingWAL{WAL: wal, writes: writes}
+		engine.stabilize(2)
+		require.Nil(t, wal.Close())
+
+		wal, err = OpenFileWAL(path)
+		require.Nil(t, err)
+		restarted := NewPBFTPoA(validators, privKey, nil, wal)
+		restarted.replay()
+		restarted.stopViewTimer()
+		require.Nil(t, wal.Close())
+
+		// the restarted validator finds the old log or the whole new one
+		assert.Equal(t, 3, restarted.lastCommitted)
+		assert.Equal(t, 1, restarted.targetView)
+		if writes == 0 {
+			assert.Equal(t, 0, restarted.stableCheckpoint)
+			continue
+		}
+		assert.Equal(t, 2, restarted.stableCheckpoint)
+		assert.Equal(t, int64(3), restarted.validatorSet.Height)
+		assert.Contains(t, restarted.viewChanges[1], engine.nodeID)
+	}
+}
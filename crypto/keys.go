@@ -112,6 +112,16 @@ type PublicKey struct {
 	key ed25519.PublicKey
 }
 
+// NewPublicKeyFromString creates a PublicKey from its hex encoding.
+func NewPublicKeyFromString(s string) *PublicKey {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		util.Logger.Error().Msgf("error decoding public key [%s]", err)
+		panic(err)
+	}
+	return PublicKeyFromBytes(b)
+}
+
 func PublicKeyFromBytes(b []byte) *PublicKey {
 	if len(b) != PubKeyLen {
 		panic("invalid public key length from bytes")
@@ -131,6 +141,10 @@ func (p *PublicKey) Bytes() []byte {
 	return p.key
 }
 
+func (p *PublicKey) String() string {
+	return hex.EncodeToString(p.key)
+}
+
 type Signature struct {
 	value []byte
 }
@@ -72,3 +72,9 @@ func TestPubKeyToAddress(t *testing.T) {
 	addr := pubKey.Address()
 	assert.Equal(t, AddressLen, len(addr.Bytes()))
 }
+
+func TestPublicKeyFromString(t *testing.T) {
+	pubKey := GeneratePrivateKey().Public()
+	parsed := NewPublicKeyFromString(pubKey.String())
+	assert.Equal(t, pubKey.Bytes(), parsed.Bytes())
+}
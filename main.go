@@ -2,6 +2,9 @@ package main
 
 import (
 	"flag"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/janrockdev/darkblock/crypto"
 	"github.com/janrockdev/darkblock/node"
@@ -16,22 +19,33 @@ func main() {
 	if *port == "" {
 		logger.Fatal().Msg("port is required")
 	}
+	var n *node.Node
 	if *port == ":3000" {
 		logger.Info().Msg("starting bootstrap & validator node on port [:3000]")
-		makeNode(*port, []string{}, true)
+		n = makeNode(*port, []string{}, true)
 	} else {
 		logger.Info().Msg("starting discovery, contacting bootstrap & validator node on port [:3000]")
-		makeNode(*port, []string{":3000"}, false)
+		n = makeNode(*port, []string{":3000"}, false)
 	}
 
-	select {} // block main thread forever
+	// run until interrupted, the storage is closed cleanly on the way out
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	if err := n.Stop(); err != nil {
+		logger.Fatal().Msgf("failed to stop node: %s", err)
+	}
 }
 
 // makeNode creates a new node with the given listen address and bootstrap nodes
 func makeNode(listenAddr string, bootstrapNodes []string, isValidator bool) *node.Node {
 	cfg := node.ServerConfig{
-		Version:    "darkblock-1",
-		ListenAddr: listenAddr,
+		Version:       "darkblock-1",
+		ListenAddr:    listenAddr,
+		Validators:    loadValidators(), // every node validates governance against the validator set
+		SyncMode:      util.LoadConfig().NETWORK.Sync,
+		Engine:        util.LoadConfig().CONSENSUS.Engine, // every node finalizes blocks the way the engine of the network does
+		Confirmations: util.LoadConfig().CONSENSUS.Confirmations,
 	}
 	if isValidator {
 		privKey, err := crypto.LoadPrivateKeyFromFile("private_key.txt") // load private key for node from file
@@ -39,9 +53,22 @@ func makeNode(listenAddr string, bootstrapNodes []string, isValidator bool) *nod
 			logger.Fatal().Msgf("failed to load private key: %s", err)
 		}
 		cfg.PrivateKey = privKey
+		cfg.WALPath = util.LoadConfig().CONSENSUS.WAL
+		if cfg.Engine == "raft" {
+			cfg.WALPath = util.LoadConfig().CONSENSUS.RaftWAL
+		}
 	}
-	n := node.NewNode(cfg, bootstrapNodes) // bootstrapNodes for consensus
+	n := node.NewNode(cfg, bootstrapNodes)
 	go n.Start(listenAddr, bootstrapNodes)
 
 	return n
 }
+
+// loadValidators reads the public keys of the consensus validator set from the config file
+func loadValidators() []*crypto.PublicKey {
+	validators := []*crypto.PublicKey{}
+	for _, key := range util.LoadConfig().CONSENSUS.Validators {
+		validators = append(validators, crypto.NewPublicKeyFromString(key))
+	}
+	return validators
+}
@@ -0,0 +1,271 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// maxOrphans bounds the blocks kept while their parent is unknown, the oldest
+// orphan is dropped first.
+const maxOrphans = 256
+
+// pruneDepth is how many blocks below the tip the block tree keeps, a fork
+// branching off deeper than that is not followed.
+const pruneDepth = 64
+
+// ErrOrphanBlock is returned for a block whose parent is not known yet. The
+// block is kept in the orphan pool and connected once the parent arrives.
+var ErrOrphanBlock = errors.New("parent block unknown")
+
+// blockNode is a block of the block tree. Every node knows the validator set
+// after its block so a branch is validated against its own governance.
+type blockNode struct {
+	block      *proto.Block
+	hash       []byte
+	parent     *blockNode
+	children   []*blockNode
+	index      int // position in the header list, the root is at 0
+	finalized  bool
+	validators *proto.ValidatorSet
+}
+
+// Reorg describes how the canonical chain changed with a block, the lists are
+// in height order. Extending the tip removes nothing.
+type Reorg struct {
+	Removed   []*proto.Block
+	Added     []*proto.Block
+	Finalized []*proto.Block // canonical blocks that became final, the chain never leaves them
+	// PrevValidators holds the validator set before each added block that
+	// changed it, by block hash, so the sets below the tip can be restored.
+	PrevValidators map[string]*proto.ValidatorSet
+}
+
+// OrphanPool holds blocks whose parent is unknown, by the hash of the parent.
+type OrphanPool struct {
+	byParent map[string][]*proto.Block
+	order    []*proto.Block
+}
+
+func NewOrphanPool() *OrphanPool {
+	return &OrphanPool{
+		byParent: make(map[string][]*proto.Block),
+	}
+}
+
+func (p *OrphanPool) Add(b *proto.Block) {
+	hash := types.HashBlock(b)
+	parent := hex.EncodeToString(b.Header.PrevHash)
+	for _, o := range p.byParent[parent] {
+		if bytes.Equal(types.HashBlock(o), hash) {
+			return
+		}
+	}
+	if len(p.order) == maxOrphans {
+		p.remove(p.order[0])
+	}
+	p.byParent[parent] = append(p.byParent[parent], b)
+	p.order = append(p.order, b)
+}
+
+// Take removes and returns the orphans waiting for a parent.
+func (p *OrphanPool) Take(parent []byte) []*proto.Block {
+	orphans := p.byParent[hex.EncodeToString(parent)]
+	for _, o := range orphans {
+		p.remove(o)
+	}
+	return orphans
+}
+
+func (p *OrphanPool) Len() int {
+	return len(p.order)
+}
+
+func (p *OrphanPool) remove(b *proto.Block) {
+	parent := hex.EncodeToString(b.Header.PrevHash)
+	siblings := p.byParent[parent]
+	for i, o := range siblings {
+		if o == b {
+			siblings = append(siblings[:i:i], siblings[i+1:]...)
+			break
+		}
+	}
+	if len(siblings) == 0 {
+		delete(p.byParent, parent)
+	} else {
+		p.byParent[parent] = siblings
+	}
+	for i, o := range p.order {
+		if o == b {
+			p.order = append(p.order[:i:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// isCanonical reports whether a node of the tree is part of the header list.
+func (c *Chain) isCanonical(n *blockNode) bool {
+	if n.index > c.headers.Height() {
+		return false
+	}
+	return bytes.Equal(types.HashHeader(c.headers.Get(n.index)), n.hash)
+}
+
+// forkPoint returns the canonical ancestor a node branches off from, the node
+// itself when it is canonical.
+func (c *Chain) forkPoint(n *blockNode) *blockNode {
+	for n != nil && !c.isCanonical(n) {
+		n = n.parent
+	}
+	return n
+}
+
+// deepestTip returns the longest branch below a node, the first one on a tie.
+func deepestTip(n *blockNode) *blockNode {
+	best := n
+	for _, child := range n.children {
+		if tip := deepestTip(child); tip.index > best.index {
+			best = tip
+		}
+	}
+	return best
+}
+
+// chooseTip applies the fork choice after a node joined the tree: the chain
+// always contains the last finalized block and beyond it the longest branch
+// wins. On a tie the current tip stays.
+func (c *Chain) chooseTip(n *blockNode) *blockNode {
+	if n.finalized && !c.isCanonical(n) {
+		if fork := c.forkPoint(n); fork != nil && fork.index >= c.finalized.index {
+			return deepestTip(n)
+		}
+		logger.Error().Msgf("finalized block [%s] conflicts with finalized block [%s]", hex.EncodeToString(n.hash)[:8], hex.EncodeToString(c.finalized.hash)[:8])
+		return c.tip
+	}
+	candidate := deepestTip(n)
+	if candidate.index <= c.tip.index {
+		return c.tip
+	}
+	if fork := c.forkPoint(candidate); fork == nil || fork.index < c.finalized.index {
+		return c.tip
+	}
+	return candidate
+}
+
+// switchTo makes the branch ending in tip the canonical chain, the header list
+// is rewound to the fork point and extended with the branch.
+func (c *Chain) switchTo(tip *blockNode) *Reorg {
+	reorg := &Reorg{}
+	if tip == c.tip {
+		return reorg
+	}
+
+	var branch []*blockNode
+	for n := tip; !c.isCanonical(n); n = n.parent {
+		branch = append(branch, n)
+	}
+	fork := branch[len(branch)-1].parent
+
+	for i := fork.index + 1; i <= c.headers.Height(); i++ {
+		if b, err := c.GetBlockByHash(types.HashHeader(c.headers.Get(i))); err == nil {
+			reorg.Removed = append(reorg.Removed, b)
+		}
+	}
+	c.headers.Rewind(fork.index)
+	for i := len(branch) - 1; i >= 0; i-- {
+		n := branch[i]
+		c.headers.Add(n.block.Header)
+		reorg.Added = append(reorg.Added, n.block)
+		if n.validators != nil && !sameValidators(n.parent.validators, n.validators) {
+			if reorg.PrevValidators == nil {
+				reorg.PrevValidators = make(map[string]*proto.ValidatorSet)
+			}
+			reorg.PrevValidators[hex.EncodeToString(n.hash)] = n.parent.validators
+		}
+	}
+
+	if len(reorg.Removed) > 0 {
+		logger.Warn().Msgf("reorganized chain at index [%d]: [%d] blocks removed, [%d] added", fork.index, len(reorg.Removed), len(reorg.Added))
+	}
+	c.tip = tip
+	c.validators = tip.validators
+	return reorg
+}
+
+// markFinalized records a block has a commit certificate, the finalized block
+// only moves forward.
+func (c *Chain) markFinalized(n *blockNode) {
+	n.finalized = true
+	if n.index > c.finalized.index {
+		if fork := c.forkPoint(n); fork != nil && fork.index >= c.finalized.index {
+			c.finalized = n
+		}
+	}
+}
+
+// prune finalizes the blocks with enough confirmations and drops the blocks
+// that can never become canonical again from the block tree: the side
+// branches forking off below the finalized block and the blocks more than
+// pruneDepth below the tip. The oldest block kept becomes the root, it is
+// final from then on.
+func (c *Chain) prune() {
+	if c.confirmations > 0 {
+		confirmed := c.tip
+		for confirmed.parent != nil && confirmed.index > c.tip.index-c.confirmations {
+			confirmed = confirmed.parent
+		}
+		c.markFinalized(confirmed)
+	}
+
+	root := c.tip
+	for root.parent != nil && root.index > c.tip.index-pruneDepth {
+		root = root.parent
+	}
+	c.reroot(root)
+}
+
+// reroot makes a canonical block the root of the block tree, the blocks below
+// it and the side branches forking off below the finalized block are dropped.
+func (c *Chain) reroot(root *blockNode) {
+	if root.index > c.finalized.index {
+		root.finalized = true
+		c.finalized = root
+	}
+
+	for child, n := c.finalized, c.finalized.parent; n != nil; child, n = n, n.parent {
+		for _, side := range n.children {
+			if side != child {
+				c.dropBranch(side)
+			}
+		}
+		n.children = []*blockNode{child}
+		if n.index < root.index {
+			delete(c.tree, hex.EncodeToString(n.hash))
+		}
+	}
+	root.parent = nil
+	c.root = root
+}
+
+// dropBranch removes a node and the nodes below it from the block tree.
+func (c *Chain) dropBranch(n *blockNode) {
+	delete(c.tree, hex.EncodeToString(n.hash))
+	for _, child := range n.children {
+		c.dropBranch(child)
+	}
+}
+
+// sameValidators reports whether a block left the validator set as it was,
+// the height of the set aside.
+func sameValidators(prev, next *proto.ValidatorSet) bool {
+	if prev == nil {
+		return false
+	}
+	next = pb.Clone(next).(*proto.ValidatorSet)
+	next.Height = prev.Height
+	return pb.Equal(prev, next)
+}
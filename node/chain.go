@@ -3,11 +3,12 @@ package node
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/janrockdev/darkblock/crypto"
 	"github.com/janrockdev/darkblock/proto"
-	"github.com/janrockdev/darkblock/services"
 	"github.com/janrockdev/darkblock/types"
 	"github.com/janrockdev/darkblock/util"
 	pb "google.golang.org/protobuf/proto"
@@ -36,6 +37,11 @@ func (list *HeaderList) Get(index int) *proto.Header {
 	return list.headers[index]
 }
 
+// Rewind drops the headers above an index.
+func (list *HeaderList) Rewind(index int) {
+	list.headers = list.headers[:index+1]
+}
+
 func (list *HeaderList) Height() int {
 	return list.Len() - 1
 }
@@ -51,83 +57,346 @@ func (list *HeaderList) Len() int {
 // 	Spend    bool
 // }
 
+// EquivocationError is returned for a block whose proposer already signed a
+// different block at the same height.
+type EquivocationError struct {
+	Evidence *proto.Evidence
+}
+
+func (e *EquivocationError) Error() string {
+	return fmt.Sprintf("validator [%s] signed two blocks at height [%d]", hex.EncodeToString(e.Evidence.PublicKey)[:8], e.Evidence.FirstBlock.Header.Height)
+}
+
 type Chain struct {
+	// mu guards the headers, the block tree and the validator set, blocks
+	// are inserted under the write lock while the node keeps reading
+	mu sync.RWMutex
+
 	txStore    TXStorer
 	blockStore BlockStorer
 	// utxoStore  UTXOStorer
-	headers *HeaderList
-}
-
-// func NewChain(bs BlockStorer, txStore TXStorer) *Chain {
-// 	chain := &Chain{
-// 		blockStore: bs,
-// 		txStore:    txStore,
-// 		//utxoStore:  NewMemoryUTXOStore(),
-// 		headers: NewHeaderList(),
-// 	}
-// 	chain.addBlock(createGenesisBlock())
-// 	return chain
-// }
+	headers    *HeaderList
+	validators *proto.ValidatorSet // on-chain validator set, nil when governance is not followed
+
+	tree      map[string]*blockNode // recent blocks by hash, side branches included
+	root      *blockNode            // oldest block of the tree, the blocks below it are final
+	tip       *blockNode
+	finalized *blockNode // last final block of the chain
+	orphans   *OrphanPool
+
+	confirmations int // blocks on top of a block that make it final, 0 waits for a commit certificate
+}
+
+// canonicalStorer is a block store that also keeps the canonical chain, the
+// chain is rebuilt from it on startup.
+type canonicalStorer interface {
+	BlockStorer
+	Canonical(fn func(*proto.Block) error) error
+	PrevValidators(hash []byte) (*proto.ValidatorSet, error)
+}
 
 func NewChain(bs BlockStorer, txStore TXStorer) *Chain {
-	db_dir := util.LoadConfig().BADGER.DataDir
 	chain := &Chain{
 		blockStore: bs,
 		txStore:    txStore,
 		//utxoStore:  NewMemoryUTXOStore(),
 		headers: NewHeaderList(),
+		tree:    make(map[string]*blockNode),
+		orphans: NewOrphanPool(),
 	}
-	// check badger db for existing blocks
-	// if there is no block, create a genesis block
-	if !services.CacheExists(db_dir) {
-		chain.addBlock(createGenesisBlock())
-	} else {
-		// connect to badger db
-		bdb, err := services.ConnectBadgerDB(db_dir)
-		if err != nil {
-			util.Logger.Error().Msgf("error connecting to badger db: [%s]", err.Error())
-			panic(err)
-		}
-		prevLastBlockBytes, _, _, err := bdb.GetLatestRecord() //prevLastPrefix
-		if err != nil {
-			util.Logger.Error().Msgf("error getting latest record from badger db: [%s]", err.Error())
-			panic(err)
+	genesis := createGenesisBlock()
+	store, ok := bs.(canonicalStorer)
+	if !ok {
+		chain.addRoot(genesis)
+		return chain
+	}
+	if err := chain.load(store, genesis); err != nil {
+		util.Logger.Error().Msgf("error loading chain from the block store: [%s]", err.Error())
+		panic(err)
+	}
+
+	return chain
+}
+
+// load rebuilds the header list from the persisted canonical chain. The
+// persisted blocks were validated when they were committed, the last
+// pruneDepth of them go back into the block tree so a fork below the tip is
+// still followed after a restart.
+func (c *Chain) load(store canonicalStorer, genesis *proto.Block) error {
+	if err := c.blockStore.Put(genesis); err != nil {
+		return err
+	}
+
+	recent := []*proto.Block{genesis}
+	err := store.Canonical(func(b *proto.Block) error {
+		tip := recent[len(recent)-1]
+		if b.Header.Height != tip.Header.Height+1 || !bytes.Equal(b.Header.PrevHash, types.HashBlock(tip)) {
+			return fmt.Errorf("block height [%d] does not extend height [%d]", b.Header.Height, tip.Header.Height)
 		}
-		prevLastBlock := &proto.Block{}
-		if err := pb.Unmarshal(prevLastBlockBytes, prevLastBlock); err != nil {
-			util.Logger.Error().Msgf("error unmarshalling block: [%s]", err.Error())
-			panic(err)
+		// blocks persisted before the hash index existed are added to it
+		if _, err := c.blockStore.Get(hex.EncodeToString(types.HashBlock(b))); err != nil {
+			if err := c.blockStore.Put(b); err != nil {
+				return err
+			}
 		}
-		if err := chain.AddBlock(prevLastBlock); err != nil {
-			util.Logger.Error().Msgf("error adding block to chain: [%s]", err.Error())
-			panic(err)
+		if len(recent) > pruneDepth {
+			c.headers.Add(recent[0].Header)
+			recent = recent[1:]
 		}
-		bdb.Close()
+		recent = append(recent, b)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return chain
+	if err := c.addRoot(recent[0]); err != nil {
+		return err
+	}
+	for _, b := range recent[1:] {
+		n := &blockNode{block: b, hash: types.HashBlock(b), parent: c.tip, index: c.tip.index + 1}
+		c.tip.children = append(c.tip.children, n)
+		c.tree[hex.EncodeToString(n.hash)] = n
+		c.headers.Add(b.Header)
+		c.tip = n
+	}
+	util.Logger.Info().Msgf("loaded chain of height [%d] from the block store", c.height())
+	return nil
 }
 
 func (c *Chain) Height() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.height()
+}
+
+func (c *Chain) height() int {
 	return c.headers.Height()
 }
 
+// Tip returns the last block of the canonical chain.
+func (c *Chain) Tip() *proto.Block {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tip.block
+}
+
+// SetValidatorSet makes the chain validate governance against a validator set
+// and follow its changes from now on. The set is the one after the tip, the
+// blocks of the tree below it get the set before the next block changed it.
+// The tree is cut where that set is not known.
+func (c *Chain) SetValidatorSet(set *proto.ValidatorSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.validators = set
+	c.tip.validators = set
+
+	store, _ := c.blockStore.(canonicalStorer)
+	for n := c.tip; n.parent != nil; n = n.parent {
+		var prev *proto.ValidatorSet
+		if store != nil {
+			var err error
+			if prev, err = store.PrevValidators(n.hash); err != nil {
+				logger.Error().Msgf("failed to load the validator set before block height [%d]: [%s]", n.block.Header.Height, err)
+				c.reroot(n)
+				return
+			}
+		}
+		if prev == nil {
+			// a block stored without the set before it may have changed it
+			if len(n.block.Evidence) > 0 || hasGovernance(n.block) {
+				c.reroot(n)
+				return
+			}
+			prev = pb.Clone(n.validators).(*proto.ValidatorSet)
+			prev.Height = int64(n.parent.block.Header.Height)
+		}
+		n.parent.validators = prev
+	}
+}
+
+// ValidatorSet returns the validator set after the last block of the chain.
+func (c *Chain) ValidatorSet() *proto.ValidatorSet {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.validators
+}
+
+// VerifyCertificate checks a commit certificate against the validator set
+// after the parent of its block, the set the block was committed under.
+func (c *Chain) VerifyCertificate(b *proto.Block, cert *proto.CommitCertificate) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.verifyCertificate(b, cert)
+}
+
+func (c *Chain) verifyCertificate(b *proto.Block, cert *proto.CommitCertificate) error {
+	parent, ok := c.tree[hex.EncodeToString(b.GetHeader().GetPrevHash())]
+	if !ok {
+		return ErrOrphanBlock
+	}
+	if parent.validators == nil {
+		return fmt.Errorf("%w: chain follows no validator set", ErrBadCertificate)
+	}
+	if err := types.VerifyBlockCertificate(cert, b, parent.validators); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadCertificate, err)
+	}
+	return nil
+}
+
+// SetConfirmations makes a block final once depth blocks are on top of it,
+// for engines that do not certify blocks. With 0 only a commit certificate
+// finalizes a block.
+func (c *Chain) SetConfirmations(depth int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.confirmations = depth
+}
+
+// Finalize makes the canonical block at a height final, the finality
+// persisted before a restart is restored with it.
+func (c *Chain) Finalize(height int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if height <= c.finalized.index || height > c.tip.index {
+		return
+	}
+	n := c.tip
+	for n.index > height {
+		n = n.parent
+	}
+	c.markFinalized(n)
+	c.prune()
+}
+
 func (c *Chain) AddBlock(b *proto.Block) error {
-	if err := c.ValidateBlock(b); err != nil {
-		return err
+	_, err := c.InsertBlock(b, nil)
+	return err
+}
+
+// InsertBlock adds a block to the block tree and applies the fork choice. A
+// block with a valid commit certificate is finalized, the chain never leaves
+// it, a certificate that does not verify rejects the block. The returned
+// reorg tells how the canonical chain changed, it is empty when the block went
+// to a side branch, and lists the blocks that became final. A known block is
+// only marked finalized.
+func (c *Chain) InsertBlock(b *proto.Block, cert *proto.CommitCertificate) (*Reorg, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	finalized := c.finalized.index
+	reorg, err := c.insertBlock(b, cert)
+	if err != nil {
+		return nil, err
 	}
-	return c.addBlock(b)
+	for i := finalized + 1; i <= c.finalized.index; i++ {
+		fb, err := c.blockByHeight(i)
+		if err != nil {
+			return nil, err
+		}
+		reorg.Finalized = append(reorg.Finalized, fb)
+	}
+	return reorg, nil
 }
 
-func (c *Chain) addBlock(b *proto.Block) error {
+func (c *Chain) insertBlock(b *proto.Block, cert *proto.CommitCertificate) (*Reorg, error) {
+	hash := types.HashBlock(b)
+	if n, ok := c.tree[hex.EncodeToString(hash)]; ok {
+		if cert == nil || n.finalized {
+			return &Reorg{}, nil
+		}
+		if err := c.verifyCertificate(b, cert); err != nil {
+			return nil, err
+		}
+		n.finalized = true
+		reorg := c.switchTo(c.chooseTip(n))
+		c.markFinalized(n)
+		c.prune()
+		return reorg, nil
+	}
+	// a canonical block pruned from the tree is known, its certificate can no
+	// longer be verified
+	if b.Header.Height <= c.root.block.Header.Height {
+		if known := c.blockAtHeight(b.Header.Height); known != nil && bytes.Equal(types.HashBlock(known), hash) {
+			if cert != nil {
+				return nil, fmt.Errorf("%w: height [%d] is below the block tree", ErrBadCertificate, b.Header.Height)
+			}
+			return &Reorg{}, nil
+		}
+	}
+
+	if err := c.validateBlock(b); err != nil {
+		if errors.Is(err, ErrOrphanBlock) {
+			c.orphans.Add(b)
+		}
+		return nil, err
+	}
+	if cert != nil {
+		if err := c.verifyCertificate(b, cert); err != nil {
+			return nil, err
+		}
+	}
+	n, err := c.addBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	n.finalized = cert != nil
+	c.connectOrphans(n)
+
+	reorg := c.switchTo(c.chooseTip(n))
+	if n.finalized {
+		c.markFinalized(n)
+	}
+	c.prune()
+	return reorg, nil
+}
 
-	if hex.EncodeToString(types.HashBlock(b))[:3] != "c95" {
-		util.Logger.Debug().Msgf("(9) Adding block [%s] to local blockchain, height [%d], headers [%d]", hex.EncodeToString(types.HashBlock(b))[:3], c.Height(), b.Header.Height)
-	} else {
-		util.Logger.Debug().Msgf("adding genesis block to local blockchain")
+// connectOrphans adds the orphans waiting for a block and for their children.
+func (c *Chain) connectOrphans(n *blockNode) {
+	queue := []*blockNode{n}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, orphan := range c.orphans.Take(parent.hash) {
+			if err := c.validateBlock(orphan); err != nil {
+				logger.Warn().Msgf("dropped orphan block height [%d]: [%s]", orphan.Header.Height, err)
+				continue
+			}
+			child, err := c.addBlock(orphan)
+			if err != nil {
+				logger.Warn().Msgf("dropped orphan block height [%d]: [%s]", orphan.Header.Height, err)
+				continue
+			}
+			queue = append(queue, child)
+		}
 	}
+}
 
+// addRoot starts the block tree from a block that was validated when it was
+// committed, the genesis block or the persisted tip, on top of the headers
+// already in the list.
+func (c *Chain) addRoot(b *proto.Block) error {
+	n := &blockNode{block: b, hash: types.HashBlock(b), index: c.headers.Len(), finalized: true, validators: c.validators}
+	c.tree[hex.EncodeToString(n.hash)] = n
+	c.root, c.tip, c.finalized = n, n, n
 	c.headers.Add(b.Header)
+	return c.blockStore.Put(b)
+}
+
+// addBlock adds a validated block below its parent in the block tree.
+func (c *Chain) addBlock(b *proto.Block) (*blockNode, error) {
+	parent := c.tree[hex.EncodeToString(b.Header.PrevHash)]
+	n := &blockNode{block: b, hash: types.HashBlock(b), parent: parent, index: parent.index + 1, validators: parent.validators}
+
+	util.Logger.Debug().Msgf("(9) Adding block [%s] to local blockchain, height [%d], headers [%d]", hex.EncodeToString(n.hash)[:3], c.height(), b.Header.Height)
+
+	if n.validators != nil {
+		n.validators = types.NextValidatorSet(n.validators, b)
+	}
+	parent.children = append(parent.children, n)
+	c.tree[hex.EncodeToString(n.hash)] = n
 
 	//for _, tx := range b.Transactions {
 	///	if err := c.txStore.Put(tx); err != nil {
@@ -164,7 +433,7 @@ func (c *Chain) addBlock(b *proto.Block) error {
 
 	//util.Logger.Debug().Msgf("blockchain height: %s", c.headers.headers)
 
-	return c.blockStore.Put(b)
+	return n, c.blockStore.Put(b)
 }
 
 func (c *Chain) GetBlockByHash(hash []byte) (*proto.Block, error) {
@@ -173,18 +442,59 @@ func (c *Chain) GetBlockByHash(hash []byte) (*proto.Block, error) {
 }
 
 func (c *Chain) GetBlockByHeight(height int) (*proto.Block, error) {
-	if c.Height() < height {
-		return nil, fmt.Errorf("block height %d is greater than chain height %d", height, c.Height())
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.blockByHeight(height)
+}
+
+func (c *Chain) blockByHeight(height int) (*proto.Block, error) {
+	if c.height() < height {
+		return nil, fmt.Errorf("block height %d is greater than chain height %d", height, c.height())
 	}
 	header := c.headers.Get(height)
 	hash := types.HashHeader(header)
 	return c.GetBlockByHash(hash)
 }
 
+// ValidateBlock runs the block rules and then the rules against the parent
+// block. The error wraps the reason the block was rejected.
 func (c *Chain) ValidateBlock(b *proto.Block) error {
-	// validate signature of the block
-	if !types.VerifyBlock(b) {
-		return fmt.Errorf("invalid block signature")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.validateBlock(b)
+}
+
+func (c *Chain) validateBlock(b *proto.Block) error {
+	for _, rule := range blockRules {
+		if err := rule(b); err != nil {
+			return err
+		}
+	}
+
+	// a second block signed by the same validator at a known height is equivocation
+	if known := c.blockAtHeight(b.Header.Height); known != nil {
+		if ev := types.NewBlockEvidence(known, b); ev != nil {
+			return &EquivocationError{Evidence: ev}
+		}
+	}
+
+	// the block has to extend a known block that is not behind the finalized block
+	parent, ok := c.tree[hex.EncodeToString(b.Header.PrevHash)]
+	if !ok {
+		// the parent of a block at the root height or below was pruned
+		if b.Header.Height <= c.root.block.Header.Height {
+			return fmt.Errorf("%w: height [%d] is below the block tree", ErrFinalizedConflict, b.Header.Height)
+		}
+		return ErrOrphanBlock
+	}
+	if fork := c.forkPoint(parent); fork == nil || fork.index < c.finalized.index {
+		return fmt.Errorf("%w: height [%d]", ErrFinalizedConflict, b.Header.Height)
+	}
+
+	for _, rule := range parentRules {
+		if err := rule(parent, b); err != nil {
+			return err
+		}
 	}
 
 	// currentBlock := &proto.Block{}
@@ -211,26 +521,46 @@ func (c *Chain) ValidateBlock(b *proto.Block) error {
 	// 	}
 	// }
 
-	if c.Height() > 0 {
-		currentBlock, err := c.GetBlockByHeight(c.Height())
-		if err != nil {
-			util.Logger.Error().Msgf("error getting block by height: [%s]", err.Error())
+	return nil
+}
+
+// validateGovernance checks the governance transactions of a block against
+// the validator set of its parent.
+func validateGovernance(validators *proto.ValidatorSet, b *proto.Block) error {
+	if validators == nil {
+		if hasGovernance(b) {
+			return fmt.Errorf("governance transaction without a validator set")
 		}
+		return nil
+	}
+	return types.VerifyBlockGovernance(validators, b)
+}
 
-		hash := types.HashBlock(currentBlock)
-		if !bytes.Equal(hash, b.Header.PrevHash) && hex.EncodeToString(hash)[:3] != "c95" {
-			util.Logger.Error().Msgf("invalid previous block hash: [%s] expected: [%s]", hex.EncodeToString(hash)[:3], hex.EncodeToString(b.Header.PrevHash)[:3])
+// hasGovernance reports whether a block carries governance transactions.
+func hasGovernance(b *proto.Block) bool {
+	for _, tx := range b.Transactions {
+		if tx.Governance != nil {
+			return true
 		}
+	}
+	return false
+}
 
-		//validate transactions (double validation)
-		for _, tx := range b.Transactions {
-			if err := c.ValidateTransaction(tx); err != nil {
-				util.Logger.Error().Msgf("validate transaction error: [%s]", err.Error())
-				return err
+// blockAtHeight returns the block of the chain with the given header height.
+func (c *Chain) blockAtHeight(height int32) *proto.Block {
+	for i := c.headers.Height(); i >= 0; i-- {
+		header := c.headers.Get(i)
+		if header.Height < height {
+			return nil
+		}
+		if header.Height == height {
+			b, err := c.GetBlockByHash(types.HashHeader(header))
+			if err != nil {
+				return nil
 			}
+			return b
 		}
 	}
-
 	return nil
 }
 
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"encoding/hex"
+	"sync"
 	"testing"
 
 	"github.com/janrockdev/darkblock/crypto"
@@ -16,6 +18,7 @@ func randomBlock(t *testing.T, chain *Chain) *proto.Block {
 	b := util.RandomBlock()
 	prevBlock, err := chain.GetBlockByHeight(chain.Height())
 	require.Nil(t, err)
+	b.Header.Height = prevBlock.Header.Height + 1
 	b.Header.PrevHash = types.HashBlock(prevBlock)
 	types.SignBlock(privKey, b)
 	return b
@@ -137,3 +140,311 @@ func TestAddBlockWithTx(t *testing.T) {
 	types.SignBlock(privKey, block)
 	require.Nil(t, chain.AddBlock(block))
 }
+
+func TestAddBlockEquivocation(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		privKey = crypto.GeneratePrivateKey()
+		first   = randomBlock(t, chain)
+	)
+	first.Header.Height = int32(chain.Height() + 1)
+	types.SignBlock(privKey, first)
+	require.Nil(t, chain.AddBlock(first))
+
+	// a second block from the same validator at the same height
+	second := util.RandomBlock()
+	second.Header.Height = first.Header.Height
+	second.Header.PrevHash = first.Header.PrevHash
+	types.SignBlock(privKey, second)
+
+	var equivocation *EquivocationError
+	require.ErrorAs(t, chain.AddBlock(second), &equivocation)
+	assert.True(t, types.VerifyEvidence(equivocation.Evidence))
+	assert.Equal(t, privKey.Public().Bytes(), equivocation.Evidence.PublicKey)
+}
+
+func TestAddBlockWithGovernance(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		keys    = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		removed = keys[3].Public()
+		g       = &proto.Governance{Action: types.GovernanceRemoveValidator, Validator: removed.Bytes(), EffectiveHeight: 3}
+	)
+	governanceBlock := func() *proto.Block {
+		b := randomBlock(t, chain)
+		b.Header.Height = 1
+		b.Transactions = []*proto.Transaction{{Version: 1, Governance: g}}
+		types.SignBlock(keys[0], b)
+		return b
+	}
+
+	// a chain without a validator set does not accept governance
+	types.ApproveGovernance(keys[0], g)
+	assert.Error(t, chain.AddBlock(governanceBlock()))
+
+	chain.SetValidatorSet(types.NewValidatorSet([]*crypto.PublicKey{keys[0].Public(), keys[1].Public(), keys[2].Public(), removed}))
+	assert.Error(t, chain.AddBlock(governanceBlock()))
+
+	types.ApproveGovernance(keys[1], g)
+	types.ApproveGovernance(keys[2], g)
+	require.Nil(t, chain.AddBlock(governanceBlock()))
+	assert.Len(t, chain.ValidatorSet().Validators, 4)
+
+	b := randomBlock(t, chain)
+	b.Header.Height = 2
+	types.SignBlock(keys[0], b)
+	require.Nil(t, chain.AddBlock(b))
+	assert.Equal(t, []*crypto.PublicKey{keys[0].Public(), keys[1].Public(), keys[2].Public()}, types.ValidatorSetKeys(chain.ValidatorSet()))
+}
+
+// childBlock returns a signed block on top of a parent block.
+func childBlock(parent *proto.Block) *proto.Block {
+	b := util.RandomBlock()
+	b.Header.Height = parent.Header.Height + 1
+	b.Header.PrevHash = types.HashBlock(parent)
+	types.SignBlock(crypto.GeneratePrivateKey(), b)
+	return b
+}
+
+func TestAddBlockOrphan(t *testing.T) {
+	var (
+		chain     = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		genesis   = chain.tip.block
+		parent    = childBlock(genesis)
+		orphan    = childBlock(parent)
+		grandkid  = childBlock(orphan)
+		unrelated = util.RandomBlock()
+	)
+	unrelated.Header.PrevHash = types.HashBlock(util.RandomBlock())
+	types.SignBlock(crypto.GeneratePrivateKey(), unrelated)
+
+	require.ErrorIs(t, chain.AddBlock(grandkid), ErrOrphanBlock)
+	require.ErrorIs(t, chain.AddBlock(orphan), ErrOrphanBlock)
+	require.ErrorIs(t, chain.AddBlock(unrelated), ErrOrphanBlock)
+	assert.Equal(t, 0, chain.Height())
+	assert.Equal(t, 3, chain.orphans.Len())
+
+	// the parent connects the waiting orphans
+	require.Nil(t, chain.AddBlock(parent))
+	assert.Equal(t, 3, chain.Height())
+	assert.Equal(t, 1, chain.orphans.Len())
+	tip, err := chain.GetBlockByHeight(3)
+	require.Nil(t, err)
+	assert.Equal(t, grandkid, tip)
+}
+
+func TestInsertBlockReorgLongest(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		genesis = chain.tip.block
+		a1      = childBlock(genesis)
+		a2      = childBlock(a1)
+		b1      = childBlock(genesis)
+		b2      = childBlock(b1)
+		b3      = childBlock(b2)
+	)
+	for _, b := range []*proto.Block{a1, a2} {
+		require.Nil(t, chain.AddBlock(b))
+	}
+
+	// a shorter or equally long branch does not replace the chain
+	for _, b := range []*proto.Block{b1, b2} {
+		reorg, err := chain.InsertBlock(b, nil)
+		require.Nil(t, err)
+		assert.Empty(t, reorg.Added)
+	}
+	head, err := chain.GetBlockByHeight(2)
+	require.Nil(t, err)
+	assert.Equal(t, a2, head)
+
+	reorg, err := chain.InsertBlock(b3, nil)
+	require.Nil(t, err)
+	assert.Equal(t, []*proto.Block{a1, a2}, reorg.Removed)
+	assert.Equal(t, []*proto.Block{b1, b2, b3}, reorg.Added)
+	assert.Equal(t, 3, chain.Height())
+	for i, b := range []*proto.Block{b1, b2, b3} {
+		got, err := chain.GetBlockByHeight(i + 1)
+		require.Nil(t, err)
+		assert.Equal(t, b, got)
+	}
+}
+
+func TestChainReadsDuringReorg(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		genesis = chain.tip.block
+		done    = make(chan struct{})
+		readers sync.WaitGroup
+	)
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// a reorg never leaves a reader with a height it cannot read
+				b, err := chain.GetBlockByHeight(chain.Height())
+				assert.Nil(t, err)
+				assert.NotNil(t, b)
+				assert.NotNil(t, chain.Tip())
+				chain.ValidatorSet()
+				chain.ValidateBlock(childBlock(genesis))
+			}
+		}()
+	}
+
+	// every branch forks at the genesis block and is longer than the last one
+	for length := 2; length < 30; length++ {
+		prev := genesis
+		for range length {
+			b := childBlock(prev)
+			_, err := chain.InsertBlock(b, nil)
+			require.Nil(t, err)
+			prev = b
+		}
+		assert.Equal(t, prev, chain.Tip())
+	}
+	close(done)
+	readers.Wait()
+}
+
+func TestInsertBlockPrunesTree(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		genesis = chain.tip.block
+		side    = childBlock(genesis)
+		blocks  = []*proto.Block{genesis}
+	)
+	require.Nil(t, chain.AddBlock(childBlock(genesis)))
+	blocks = append(blocks, chain.tip.block)
+	_, err := chain.InsertBlock(side, nil)
+	require.Nil(t, err)
+	for i := 0; i < pruneDepth+10; i++ {
+		b := childBlock(blocks[len(blocks)-1])
+		require.Nil(t, chain.AddBlock(b))
+		blocks = append(blocks, b)
+	}
+
+	// the tree keeps pruneDepth blocks below the tip, the side branch is gone
+	assert.Len(t, chain.tree, pruneDepth+1)
+	assert.NotContains(t, chain.tree, hex.EncodeToString(types.HashBlock(side)))
+	assert.Equal(t, chain.tip.index-pruneDepth, chain.root.index)
+	assert.Equal(t, chain.root, chain.finalized)
+	assert.Nil(t, chain.root.parent)
+
+	// a fork below the root is not followed, a pruned canonical block is known
+	require.ErrorIs(t, chain.AddBlock(childBlock(blocks[5])), ErrFinalizedConflict)
+	reorg, err := chain.InsertBlock(blocks[5], nil)
+	require.Nil(t, err)
+	assert.Empty(t, reorg.Added)
+
+	// a fork above the root still is
+	fork := childBlock(blocks[len(blocks)-3])
+	reorg, err = chain.InsertBlock(fork, nil)
+	require.Nil(t, err)
+	assert.Empty(t, reorg.Added)
+	assert.Contains(t, chain.tree, hex.EncodeToString(types.HashBlock(fork)))
+}
+
+func TestInsertBlockConfirmations(t *testing.T) {
+	var (
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		genesis = chain.tip.block
+		blocks  = []*proto.Block{genesis}
+	)
+	chain.SetConfirmations(2)
+
+	// a block is final with two blocks on top of it
+	for i := 1; i <= 4; i++ {
+		b := childBlock(blocks[i-1])
+		reorg, err := chain.InsertBlock(b, nil)
+		require.Nil(t, err)
+		blocks = append(blocks, b)
+		if i < 3 {
+			assert.Empty(t, reorg.Finalized)
+			continue
+		}
+		assert.Equal(t, []*proto.Block{blocks[i-2]}, reorg.Finalized)
+	}
+	require.ErrorIs(t, chain.AddBlock(childBlock(blocks[1])), ErrFinalizedConflict)
+
+	// the finality persisted before a restart is restored
+	chain.Finalize(3)
+	assert.Equal(t, 3, chain.finalized.index)
+	require.ErrorIs(t, chain.AddBlock(childBlock(blocks[2])), ErrFinalizedConflict)
+}
+
+// certify returns the commit certificate of a block signed by validators.
+func certify(b *proto.Block, validators ...*crypto.PrivateKey) *proto.CommitCertificate {
+	cert := &proto.CommitCertificate{Sequence: int64(b.Header.Height), BlockHash: types.HashBlock(b)}
+	hash := types.HashConsensusMessage("Commit", 0, cert.Sequence, cert.BlockHash)
+	for _, key := range validators {
+		cert.Votes = append(cert.Votes, &proto.CommitVote{PublicKey: key.Public().Bytes(), Signature: key.Sign(hash).Bytes()})
+	}
+	return cert
+}
+
+func TestInsertBlockFinalizedFirst(t *testing.T) {
+	var (
+		validator = crypto.GeneratePrivateKey()
+		other     = crypto.GeneratePrivateKey()
+		chain     = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		genesis   = chain.tip.block
+		a1        = childBlock(genesis)
+		a2        = childBlock(a1)
+		b1        = childBlock(genesis)
+	)
+	chain.SetValidatorSet(types.NewValidatorSet([]*crypto.PublicKey{validator.Public(), other.Public()}))
+	// the header hash stays the same
+	for _, b := range []*proto.Block{a1, a2} {
+		types.SignBlock(validator, b)
+	}
+	types.SignBlock(other, b1)
+	require.Nil(t, chain.AddBlock(a1))
+	require.Nil(t, chain.AddBlock(a2))
+	require.Nil(t, chain.AddBlock(b1))
+
+	// a certificate that does not verify is no reason to leave the chain
+	_, err := chain.InsertBlock(b1, certify(b1, crypto.GeneratePrivateKey()))
+	assert.ErrorIs(t, err, ErrBadCertificate)
+	_, err = chain.InsertBlock(b1, certify(a1, validator))
+	assert.ErrorIs(t, err, ErrBadCertificate)
+	assert.Equal(t, 2, chain.Height())
+
+	// a commit certificate for the shorter branch makes it the chain
+	reorg, err := chain.InsertBlock(b1, certify(b1, validator, other))
+	require.Nil(t, err)
+	assert.Equal(t, []*proto.Block{a1, a2}, reorg.Removed)
+	assert.Equal(t, []*proto.Block{b1}, reorg.Added)
+	assert.Equal(t, []*proto.Block{b1}, reorg.Finalized)
+	assert.Equal(t, 1, chain.Height())
+
+	// a longer branch that leaves the finalized block is rejected
+	a3 := childBlock(a2)
+	types.SignBlock(validator, a3)
+	reorg, err = chain.InsertBlock(a3, nil)
+	assert.Error(t, err)
+	assert.Nil(t, reorg)
+	assert.Equal(t, 1, chain.Height())
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	var (
+		keys    = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		chain   = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		genesis = chain.tip.block
+		b1      = childBlock(genesis)
+	)
+	chain.SetValidatorSet(types.NewValidatorSet([]*crypto.PublicKey{keys[0].Public(), keys[1].Public(), keys[2].Public(), keys[3].Public()}))
+	cert := certify(b1, keys[:3]...)
+
+	// the votes are checked against the validator set of the parent
+	assert.Nil(t, chain.VerifyCertificate(b1, cert))
+	assert.ErrorIs(t, chain.VerifyCertificate(childBlock(b1), cert), ErrOrphanBlock)
+	cert.Votes = cert.Votes[:2]
+	assert.ErrorIs(t, chain.VerifyCertificate(b1, cert), ErrBadCertificate)
+}
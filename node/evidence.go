@@ -0,0 +1,122 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/janrockdev/darkblock/consensus"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+)
+
+// EvidencePool holds evidence of equivocation until a block includes it.
+type EvidencePool struct {
+	lock     sync.RWMutex
+	items    map[string]*proto.Evidence
+	punished map[string]bool // validators whose evidence is already on chain
+}
+
+// NewEvidencePool creates a new evidence pool.
+func NewEvidencePool() *EvidencePool {
+	return &EvidencePool{
+		items:    make(map[string]*proto.Evidence),
+		punished: make(map[string]bool),
+	}
+}
+
+// Add adds evidence against a validator that is not punished yet, it reports
+// whether the evidence is new.
+func (pool *EvidencePool) Add(ev *proto.Evidence) bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	accused := hex.EncodeToString(ev.PublicKey)
+	if pool.punished[accused] {
+		return false
+	}
+	// one piece of evidence per validator is enough
+	for _, item := range pool.items {
+		if hex.EncodeToString(item.PublicKey) == accused {
+			return false
+		}
+	}
+	pool.items[hex.EncodeToString(types.HashEvidence(ev))] = ev
+	return true
+}
+
+// All returns the pending evidence ordered by hash.
+func (pool *EvidencePool) All() []*proto.Evidence {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	keys := make([]string, 0, len(pool.items))
+	for k := range pool.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	list := make([]*proto.Evidence, len(keys))
+	for i, k := range keys {
+		list[i] = pool.items[k]
+	}
+	return list
+}
+
+// Len returns the number of pending evidence.
+func (pool *EvidencePool) Len() int {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	return len(pool.items)
+}
+
+// Punish drops the evidence against a validator once a block included it.
+func (pool *EvidencePool) Punish(pubKey []byte) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	accused := hex.EncodeToString(pubKey)
+	pool.punished[accused] = true
+	for k, item := range pool.items {
+		if hex.EncodeToString(item.PublicKey) == accused {
+			delete(pool.items, k)
+		}
+	}
+}
+
+// HandleEvidence takes evidence gossiped by a peer.
+func (n *Node) HandleEvidence(ctx context.Context, ev *proto.Evidence) (*proto.Ack, error) {
+	if !types.VerifyEvidence(ev) {
+		return nil, fmt.Errorf("invalid evidence")
+	}
+	n.addEvidence(ev)
+
+	return &proto.Ack{}, nil
+}
+
+// addEvidence keeps new evidence for the next block and gossips it.
+func (n *Node) addEvidence(ev *proto.Evidence) {
+	if !n.evidence.Add(ev) {
+		return
+	}
+	n.Logger.Warn().Msgf("evidence of equivocation against validator [%s]", hex.EncodeToString(ev.PublicKey))
+	n.broadcast(ev)
+}
+
+// evidenceLoop collects the evidence detected by the consensus engine.
+func (n *Node) evidenceLoop(reporter consensus.EvidenceReporter) {
+	for {
+		select {
+		case <-n.quit:
+			return
+		case ev, ok := <-reporter.Evidence():
+			if !ok {
+				return
+			}
+			n.addEvidence(ev)
+		}
+	}
+}
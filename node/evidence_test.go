@@ -0,0 +1,45 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func blockEvidence(t *testing.T, privKey *crypto.PrivateKey) *proto.Evidence {
+	blocks := make([]*proto.Block, 2)
+	for i := range blocks {
+		blocks[i] = util.RandomBlock()
+		blocks[i].Header.Height = 1
+		types.SignBlock(privKey, blocks[i])
+	}
+	ev := types.NewBlockEvidence(blocks[0], blocks[1])
+	require.NotNil(t, ev)
+	return ev
+}
+
+func TestEvidencePool(t *testing.T) {
+	var (
+		pool    = NewEvidencePool()
+		accused = crypto.GeneratePrivateKey()
+		ev      = blockEvidence(t, accused)
+	)
+	assert.True(t, pool.Add(ev))
+	assert.False(t, pool.Add(ev))
+
+	// one piece of evidence per validator is kept
+	assert.False(t, pool.Add(blockEvidence(t, accused)))
+	assert.True(t, pool.Add(blockEvidence(t, crypto.GeneratePrivateKey())))
+	assert.Equal(t, 2, pool.Len())
+	assert.Len(t, pool.All(), 2)
+
+	// once included in a block, evidence against the validator is dropped for good
+	pool.Punish(accused.Public().Bytes())
+	assert.Equal(t, 1, pool.Len())
+	assert.False(t, pool.Add(ev))
+}
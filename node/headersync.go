@@ -0,0 +1,240 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"google.golang.org/grpc"
+)
+
+// SyncHeaders selects the headers-first sync, the default sync streams whole
+// blocks from one peer after the other.
+const SyncHeaders = "headers"
+
+const (
+	headersPerRound = 2048 // headers validated before their bodies are downloaded
+	bodiesPerBatch  = 128  // bodies requested from a peer at once
+)
+
+// GetHeaders streams the signed headers of the persisted blocks of a range.
+func (n *Node) GetHeaders(r *proto.BlockRange, stream grpc.ServerStreamingServer[proto.Block]) error {
+	to := int(r.To)
+	if height := n.storage.Height(); to <= 0 || to > height {
+		to = height
+	}
+	for from := max(int(r.From), 1); from <= to; from += syncBatchSize {
+		results, err := n.storage.ReadBlocks(from, min(from+syncBatchSize-1, to))
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			if err := stream.Send(types.SignedHeader(res.Block)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncHeadersFirst catches up in rounds: the header chain of a round comes
+// from one peer and is validated first, then the bodies are downloaded in
+// parallel from all peers, checked against the headers and applied in order.
+func (n *Node) syncHeadersFirst(peers []proto.NodeClient) error {
+	n.syncLock.Lock()
+	defer n.syncLock.Unlock()
+
+	for {
+		from := n.storage.Height() + 1
+		prevHash, err := n.tipHash(from - 1)
+		if err != nil {
+			return err
+		}
+
+		var headers []*proto.Block
+		for _, peer := range peers {
+			if headers, err = fetchHeaders(peer, from, prevHash); err == nil {
+				break
+			}
+			n.Logger.Warn().Msgf("failed to fetch headers from height [%d]: [%s]", from, err)
+		}
+		if err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			return nil
+		}
+
+		bodies, err := downloadBodies(peers, headers)
+		if err != nil {
+			return err
+		}
+		for _, res := range bodies {
+			if err := n.applyBlock(res.Block, res.Certificate); err != nil {
+				return fmt.Errorf("block height [%d]: %w", res.Block.Header.Height, err)
+			}
+		}
+		n.Logger.Info().Msgf("synced [%d] blocks from height [%d] headers first from [%d] peers", len(bodies), from, len(peers))
+
+		if len(headers) < headersPerRound {
+			return nil
+		}
+	}
+}
+
+// tipHash returns the hash of the persisted block at height, the genesis block
+// at height 0.
+func (n *Node) tipHash(height int) ([]byte, error) {
+	if height == 0 {
+		genesis, err := n.chain.GetBlockByHeight(0)
+		if err != nil {
+			return nil, err
+		}
+		return types.HashBlock(genesis), nil
+	}
+	tip, err := n.storage.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return types.HashBlock(tip), nil
+}
+
+// fetchHeaders downloads the headers of one round and validates the chain they
+// form on top of the block with prevHash.
+func fetchHeaders(peer proto.NodeClient, from int, prevHash []byte) ([]*proto.Block, error) {
+	stream, err := peer.GetHeaders(context.Background(), &proto.BlockRange{From: int32(from), To: int32(from + headersPerRound - 1)})
+	if err != nil {
+		return nil, err
+	}
+
+	headers := []*proto.Block{}
+	for {
+		header, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return headers, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyHeader(header, from+len(headers), prevHash); err != nil {
+			return nil, err
+		}
+		prevHash = types.HashBlock(header)
+		headers = append(headers, header)
+	}
+}
+
+// verifyHeader checks a signed header links to its parent and carries a valid
+// proposer signature.
+func verifyHeader(header *proto.Block, height int, prevHash []byte) error {
+	if header.GetHeader() == nil || len(header.Transactions) > 0 {
+		return fmt.Errorf("not a signed header")
+	}
+	if int(header.Header.Height) != height {
+		return fmt.Errorf("header height [%d] where [%d] was expected", header.Header.Height, height)
+	}
+	if !bytes.Equal(header.Header.PrevHash, prevHash) {
+		return fmt.Errorf("header height [%d] does not link to its parent", height)
+	}
+	if !types.VerifyBlock(header) {
+		return fmt.Errorf("invalid proposer signature on header height [%d]", height)
+	}
+	return nil
+}
+
+// downloadBodies fetches the bodies of validated headers in batches, one
+// worker per peer. A batch a peer fails to deliver goes to the other peers and
+// the peer gets no further batches.
+func downloadBodies(peers []proto.NodeClient, headers []*proto.Block) ([]*proto.BlockSearchResult, error) {
+	var (
+		batches   = (len(headers) + bodiesPerBatch - 1) / bodiesPerBatch
+		jobs      = make(chan int, batches)
+		bodies    = make([]*proto.BlockSearchResult, len(headers))
+		completed atomic.Int32
+		wg        sync.WaitGroup
+	)
+	for start := 0; start < len(headers); start += bodiesPerBatch {
+		jobs <- start
+	}
+
+	for _, peer := range peers {
+		wg.Add(1)
+		go func(peer proto.NodeClient) {
+			defer wg.Done()
+			for start := range jobs {
+				end := min(start+bodiesPerBatch, len(headers))
+				batch, err := fetchBodies(peer, headers[start:end])
+				if err != nil {
+					logger.Warn().Msgf("failed to fetch block bodies from height [%d]: [%s]", headers[start].Header.Height, err)
+					jobs <- start
+					return
+				}
+				copy(bodies[start:end], batch)
+				if int(completed.Add(1)) == batches {
+					close(jobs)
+				}
+			}
+		}(peer)
+	}
+	wg.Wait()
+
+	if int(completed.Load()) != batches {
+		return nil, fmt.Errorf("no peer delivered the block bodies")
+	}
+	return bodies, nil
+}
+
+// fetchBodies downloads the blocks of a run of headers from a peer and checks
+// each body against its header.
+func fetchBodies(peer proto.NodeClient, headers []*proto.Block) ([]*proto.BlockSearchResult, error) {
+	stream, err := peer.GetBlocks(context.Background(), &proto.BlockRange{
+		From: headers[0].Header.Height,
+		To:   headers[len(headers)-1].Header.Height,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bodies := make([]*proto.BlockSearchResult, 0, len(headers))
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bodies) == len(headers) {
+			return nil, fmt.Errorf("more blocks than requested")
+		}
+		if err := verifyBody(res.GetBlock(), headers[len(bodies)]); err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, res)
+	}
+	if len(bodies) != len(headers) {
+		return nil, fmt.Errorf("[%d] of [%d] blocks delivered", len(bodies), len(headers))
+	}
+	return bodies, nil
+}
+
+// verifyBody checks a downloaded block has the validated header and that the
+// header commits to its transactions and evidence.
+func verifyBody(b *proto.Block, header *proto.Block) error {
+	if b.GetHeader() == nil || !bytes.Equal(types.HashBlock(b), types.HashBlock(header)) {
+		return fmt.Errorf("block does not match header height [%d]", header.Header.Height)
+	}
+	if len(b.Transactions) > 0 && !types.VerifyRootHash(b) {
+		return fmt.Errorf("transactions do not match the root hash of height [%d]", header.Header.Height)
+	}
+	if !types.VerifyBlockEvidence(b) {
+		return fmt.Errorf("evidence does not match header height [%d]", header.Header.Height)
+	}
+	return nil
+}
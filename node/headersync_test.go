@@ -0,0 +1,133 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// sliceStream streams a fixed list of messages.
+type sliceStream[T any] struct {
+	grpc.ClientStream
+	items []*T
+}
+
+func (s *sliceStream[T]) Recv() (*T, error) {
+	if len(s.items) == 0 {
+		return nil, io.EOF
+	}
+	item := s.items[0]
+	s.items = s.items[1:]
+	return item, nil
+}
+
+// fakePeer serves blocks from memory, a broken peer fails every request and a
+// lying peer serves bodies that do not match the headers.
+type fakePeer struct {
+	proto.NodeClient
+	blocks []*proto.Block // block at height i+1
+	broken bool
+	lying  bool
+}
+
+func (p *fakePeer) GetBlocks(ctx context.Context, r *proto.BlockRange, opts ...grpc.CallOption) (grpc.ServerStreamingClient[proto.BlockSearchResult], error) {
+	if p.broken {
+		return nil, fmt.Errorf("peer is down")
+	}
+	results := []*proto.BlockSearchResult{}
+	for height := int(r.From); height <= int(r.To) && height <= len(p.blocks); height++ {
+		b := p.blocks[height-1]
+		if p.lying {
+			b = &proto.Block{Header: b.Header, PublicKey: b.PublicKey, Signature: b.Signature, Transactions: []*proto.Transaction{{Version: 2}}}
+		}
+		results = append(results, &proto.BlockSearchResult{Block: b})
+	}
+	return &sliceStream[proto.BlockSearchResult]{items: results}, nil
+}
+
+func (p *fakePeer) GetHeaders(ctx context.Context, r *proto.BlockRange, opts ...grpc.CallOption) (grpc.ServerStreamingClient[proto.Block], error) {
+	if p.broken {
+		return nil, fmt.Errorf("peer is down")
+	}
+	headers := []*proto.Block{}
+	for height := int(r.From); height <= int(r.To) && height <= len(p.blocks); height++ {
+		headers = append(headers, types.SignedHeader(p.blocks[height-1]))
+	}
+	return &sliceStream[proto.Block]{items: headers}, nil
+}
+
+// linkedBlocks returns n signed blocks with transactions on top of prevHash.
+func linkedBlocks(t *testing.T, n int, prevHash []byte) []*proto.Block {
+	privKey := crypto.GeneratePrivateKey()
+	blocks := make([]*proto.Block, n)
+	for i := range blocks {
+		b := util.RandomBlock()
+		b.Header.Height = int32(i + 1)
+		b.Header.PrevHash = prevHash
+		b.Transactions = []*proto.Transaction{{Version: 1, Timestamp: int64(i)}}
+		tree, err := types.GetMerkleTree(b)
+		require.Nil(t, err)
+		b.Header.RootHash = tree.MerkleRoot()
+		types.SignBlock(privKey, b)
+		blocks[i] = b
+		prevHash = types.HashBlock(b)
+	}
+	return blocks
+}
+
+func TestFetchHeadersValidatesChain(t *testing.T) {
+	var (
+		genesis = types.HashBlock(util.RandomBlock())
+		blocks  = linkedBlocks(t, 10, genesis)
+		peer    = &fakePeer{blocks: blocks}
+	)
+	headers, err := fetchHeaders(peer, 1, genesis)
+	require.Nil(t, err)
+	require.Len(t, headers, 10)
+	assert.Empty(t, headers[0].Transactions)
+
+	// headers on top of another parent do not link
+	_, err = fetchHeaders(peer, 1, types.HashBlock(util.RandomBlock()))
+	assert.Error(t, err)
+
+	// a header changed after it was signed is rejected
+	blocks[4] = &proto.Block{Header: &proto.Header{Height: 5, PrevHash: blocks[4].Header.PrevHash}, PublicKey: blocks[4].PublicKey, Signature: blocks[4].Signature}
+	_, err = fetchHeaders(peer, 1, genesis)
+	assert.Error(t, err)
+}
+
+func TestDownloadBodiesFromPeers(t *testing.T) {
+	var (
+		genesis = types.HashBlock(util.RandomBlock())
+		blocks  = linkedBlocks(t, 3*bodiesPerBatch+5, genesis)
+		peers   = []proto.NodeClient{
+			&fakePeer{blocks: blocks, broken: true},
+			&fakePeer{blocks: blocks, lying: true},
+			&fakePeer{blocks: blocks},
+			&fakePeer{blocks: blocks},
+		}
+	)
+	headers, err := fetchHeaders(peers[2], 1, genesis)
+	require.Nil(t, err)
+
+	// the batches of the broken and the lying peer are delivered by the others
+	bodies, err := downloadBodies(peers, headers)
+	require.Nil(t, err)
+	require.Len(t, bodies, len(blocks))
+	for i, res := range bodies {
+		assert.Equal(t, blocks[i], res.Block)
+	}
+
+	// without an honest peer the bodies cannot be downloaded
+	_, err = downloadBodies(peers[:2], headers)
+	assert.Error(t, err)
+}
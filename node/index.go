@@ -0,0 +1,379 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/services"
+	"github.com/janrockdev/darkblock/types"
+	"golang.org/x/crypto/sha3"
+)
+
+// indexVersion is the version of the indexes kept for the canonical chain, a
+// database with an older version is reindexed when opened.
+const indexVersion = 3
+
+// ErrBadPageToken is returned for a page token of another listing.
+var ErrBadPageToken = errors.New("invalid page token")
+
+// ErrFieldNotIndexed is returned when searching a payload field that is not
+// configured for indexing.
+var ErrFieldNotIndexed = errors.New("payload field not indexed")
+
+var (
+	txIndexNamespace      = []byte("txIndex")
+	addressIndexNamespace = []byte("addrIndex")
+	payloadIndexNamespace = []byte("payloadIndex")
+	fieldIndexNamespace   = []byte("fieldIndex")
+	indexVersionKey       = []byte("indexVersion")
+	payloadFieldsKey      = []byte("payloadFields")
+)
+
+// indexBlock stores a block of the canonical chain and indexes it by height
+// and its transactions by hash, address and payload.
+func (s *Storage) indexBlock(txn services.Txn, b *proto.Block) error {
+	hash := types.HashBlock(b)
+	if err := putBlock(txn, b); err != nil {
+		return err
+	}
+	if err := txn.Put(heightIndexNamespace, heightKey(b.Header.Height), []byte(hex.EncodeToString(hash))); err != nil {
+		return err
+	}
+	for i, tx := range b.Transactions {
+		loc := encodeTxLocation(b.Header.Height, hash, i)
+		for _, key := range txKeys(tx) {
+			if err := txn.Put(txIndexNamespace, key, loc); err != nil {
+				return err
+			}
+		}
+		txHash := []byte(hex.EncodeToString(types.HashTransaction(tx)))
+		for _, entry := range s.listEntries(tx) {
+			if err := txn.Put(entry.namespace, listKey(entry.prefix, b.Header.Height, i), txHash); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// unindexBlock removes a block that left the canonical chain from the indexes,
+// the block itself stays in the store.
+func (s *Storage) unindexBlock(txn services.Txn, b *proto.Block) error {
+	if err := txn.Delete(heightIndexNamespace, heightKey(b.Header.Height)); err != nil {
+		return err
+	}
+	hash := types.HashBlock(b)
+	for i, tx := range b.Transactions {
+		for _, key := range txKeys(tx) {
+			// a transaction included again on the new branch keeps its entry
+			locBytes, err := txn.Get(txIndexNamespace, key)
+			if err != nil {
+				continue
+			}
+			if _, blockHash, _, err := decodeTxLocation(locBytes); err == nil && string(blockHash) != string(hash) {
+				continue
+			}
+			if err := txn.Delete(txIndexNamespace, key); err != nil {
+				return err
+			}
+		}
+		for _, entry := range s.listEntries(tx) {
+			if err := txn.Delete(entry.namespace, listKey(entry.prefix, b.Header.Height, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// txKeys returns the keys a transaction is indexed under, its hash and its
+// hash without the signature and public key of the first input.
+func txKeys(tx *proto.Transaction) [][]byte {
+	keys := [][]byte{[]byte(hex.EncodeToString(types.HashTransaction(tx)))}
+	if len(tx.Inputs) > 0 {
+		keys = append(keys, []byte(hex.EncodeToString(types.HashTransactionNoSigPuK(tx))))
+	}
+	return keys
+}
+
+// listEntry is a listing a transaction appears in, the transactions of a
+// listing share the key prefix.
+type listEntry struct {
+	namespace []byte
+	prefix    []byte
+}
+
+// listEntries returns the listings of a transaction: its addresses, its
+// payloads and the indexed fields of its JSON payloads.
+func (s *Storage) listEntries(tx *proto.Transaction) []listEntry {
+	var (
+		entries []listEntry
+		seen    = map[string]bool{}
+	)
+	add := func(namespace, prefix []byte) {
+		if key := string(namespace) + "/" + string(prefix); !seen[key] {
+			seen[key] = true
+			entries = append(entries, listEntry{namespace: namespace, prefix: prefix})
+		}
+	}
+	for _, address := range txAddresses(tx) {
+		add(addressIndexNamespace, addressPrefix(address))
+	}
+	for _, output := range tx.Outputs {
+		if len(output.Payload) == 0 {
+			continue
+		}
+		add(payloadIndexNamespace, payloadPrefix(output.Payload))
+		for field, value := range payloadFields(output.Payload, s.payloadFields) {
+			add(fieldIndexNamespace, fieldPrefix(field, value))
+		}
+	}
+	return entries
+}
+
+// txAddresses returns the addresses a transaction was sent to or by, the
+// output addresses and the addresses of the input public keys.
+func txAddresses(tx *proto.Transaction) [][]byte {
+	var addresses [][]byte
+	for _, input := range tx.Inputs {
+		if len(input.PublicKey) == crypto.PubKeyLen {
+			addresses = append(addresses, crypto.PublicKeyFromBytes(input.PublicKey).Address().Bytes())
+		}
+	}
+	for _, output := range tx.Outputs {
+		if len(output.Address) == crypto.AddressLen {
+			addresses = append(addresses, output.Address)
+		}
+	}
+	return addresses
+}
+
+// payloadFields returns the values of the given top-level fields of a JSON
+// object payload. Strings are taken as they are, other values as compact JSON.
+func payloadFields(payload []byte, fields []string) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil
+	}
+	values := map[string]string{}
+	for _, field := range fields {
+		raw, ok := object[field]
+		if !ok {
+			continue
+		}
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			values[field] = str
+			continue
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err == nil {
+			values[field] = compact.String()
+		}
+	}
+	return values
+}
+
+// listKey orders the transactions of a listing by height and position.
+func listKey(prefix []byte, height int32, position int) []byte {
+	return []byte(fmt.Sprintf("%s%016d_%08d", prefix, height, position))
+}
+
+func addressPrefix(address []byte) []byte {
+	return []byte(hex.EncodeToString(address) + "_")
+}
+
+// payloadPrefix keys a payload by its hash, payloads are of any size.
+func payloadPrefix(payload []byte) []byte {
+	hash := sha3.Sum256(payload)
+	return []byte(hex.EncodeToString(hash[:]) + "_")
+}
+
+func fieldPrefix(field, value string) []byte {
+	hash := sha3.Sum256([]byte(field + "\x00" + value))
+	return []byte(hex.EncodeToString(hash[:]) + "_")
+}
+
+// encodeTxLocation stores the place of a transaction as height_hash_position.
+func encodeTxLocation(height int32, blockHash []byte, position int) []byte {
+	return []byte(fmt.Sprintf("%016d_%s_%d", height, hex.EncodeToString(blockHash), position))
+}
+
+func decodeTxLocation(locBytes []byte) (int, []byte, int, error) {
+	parts := strings.Split(string(locBytes), "_")
+	if len(parts) != 3 {
+		return 0, nil, 0, fmt.Errorf("malformed tx location [%s]", locBytes)
+	}
+	height, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("malformed tx location [%s]: %w", locBytes, err)
+	}
+	blockHash, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("malformed tx location [%s]: %w", locBytes, err)
+	}
+	position, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("malformed tx location [%s]: %w", locBytes, err)
+	}
+	return height, blockHash, position, nil
+}
+
+// Transaction returns an included transaction by either of its hashes together
+// with the block that contains it.
+func (s *Storage) Transaction(hash []byte) (*proto.TxSearchResult, error) {
+	locBytes, err := s.db.Get(txIndexNamespace, []byte(hex.EncodeToString(hash)))
+	if err != nil {
+		return nil, fmt.Errorf("tx [%s] not found", hex.EncodeToString(hash))
+	}
+	height, blockHash, position, err := decodeTxLocation(locBytes)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.blocks.Get(hex.EncodeToString(blockHash))
+	if err != nil {
+		return nil, err
+	}
+	if position >= len(b.Transactions) {
+		return nil, fmt.Errorf("tx [%s] not found in block [%s]", hex.EncodeToString(hash), hex.EncodeToString(blockHash))
+	}
+	return &proto.TxSearchResult{
+		Transaction: b.Transactions[position],
+		BlockHash:   blockHash,
+		BlockHeight: int32(height),
+		TxIndex:     int32(position),
+	}, nil
+}
+
+// TransactionsByAddress returns a page of the transactions sent to or by an
+// address, oldest first, and the token of the next page, nil on the last page.
+func (s *Storage) TransactionsByAddress(address []byte, limit int, pageToken []byte) ([]*proto.TxSearchResult, []byte, error) {
+	return s.listTransactions(addressIndexNamespace, addressPrefix(address), limit, pageToken)
+}
+
+// TransactionsByPayload returns a page of the transactions with an output
+// carrying exactly the given payload.
+func (s *Storage) TransactionsByPayload(payload []byte, limit int, pageToken []byte) ([]*proto.TxSearchResult, []byte, error) {
+	return s.listTransactions(payloadIndexNamespace, payloadPrefix(payload), limit, pageToken)
+}
+
+// TransactionsByField returns a page of the transactions with a JSON payload
+// whose top-level field has the given value.
+func (s *Storage) TransactionsByField(field, value string, limit int, pageToken []byte) ([]*proto.TxSearchResult, []byte, error) {
+	for _, indexed := range s.payloadFields {
+		if indexed == field {
+			return s.listTransactions(fieldIndexNamespace, fieldPrefix(field, value), limit, pageToken)
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: [%s]", ErrFieldNotIndexed, field)
+}
+
+// listTransactions returns a page of a listing, oldest first, and the token
+// of the next page, nil on the last page.
+func (s *Storage) listTransactions(namespace, prefix []byte, limit int, pageToken []byte) ([]*proto.TxSearchResult, []byte, error) {
+	if len(pageToken) > 0 && !bytes.HasPrefix(pageToken, prefix) {
+		return nil, nil, fmt.Errorf("%w: token of another listing", ErrBadPageToken)
+	}
+
+	var (
+		results []*proto.TxSearchResult
+		next    []byte
+	)
+	// one record more than the page tells whether there is a next page
+	err := s.db.Scan(namespace, prefix, pageToken, limit+1, func(key, txHash []byte) error {
+		if len(results) == limit {
+			next = key
+			return nil
+		}
+		hash, err := hex.DecodeString(string(txHash))
+		if err != nil {
+			return err
+		}
+		res, err := s.Transaction(hash)
+		if err != nil {
+			return err
+		}
+		results = append(results, res)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return results, next, nil
+}
+
+// reindex rebuilds the indexes of the canonical chain when they were written
+// by an older version or for other payload fields, one transaction per block.
+func (s *Storage) reindex() error {
+	version, err := s.metaValue(indexVersionKey)
+	if err != nil {
+		return err
+	}
+	fields, err := s.metaValue(payloadFieldsKey)
+	if err != nil {
+		return err
+	}
+	if version == strconv.Itoa(indexVersion) && fields == strings.Join(s.payloadFields, ",") {
+		return nil
+	}
+
+	// entries of fields no longer configured would never be removed
+	if err := s.clearNamespace(fieldIndexNamespace); err != nil {
+		return err
+	}
+	indexed := 0
+	err = s.blocks.Canonical(func(b *proto.Block) error {
+		indexed++
+		return s.db.Update(func(txn services.Txn) error {
+			return s.indexBlock(txn, b)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if indexed > 0 {
+		logger.Info().Msgf("reindexed [%d] blocks to index version [%d] with payload fields %v", indexed, indexVersion, s.payloadFields)
+	}
+	return s.db.Update(func(txn services.Txn) error {
+		if err := txn.Put(metaNamespace, indexVersionKey, []byte(strconv.Itoa(indexVersion))); err != nil {
+			return err
+		}
+		return txn.Put(metaNamespace, payloadFieldsKey, []byte(strings.Join(s.payloadFields, ",")))
+	})
+}
+
+// metaValue returns a value of the meta namespace, empty when it is not set.
+func (s *Storage) metaValue(key []byte) (string, error) {
+	if ok, err := s.db.Has(metaNamespace, key); err != nil || !ok {
+		return "", err
+	}
+	value, err := s.db.Get(metaNamespace, key)
+	return string(value), err
+}
+
+func (s *Storage) clearNamespace(namespace []byte) error {
+	keys := [][]byte{}
+	err := s.db.Iterate(namespace, func(key, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.db.Delete(namespace, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
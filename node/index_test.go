@@ -0,0 +1,183 @@
+package node
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockWithTxs builds a signed child block carrying the given transactions.
+func blockWithTxs(parent *proto.Block, txs ...*proto.Transaction) *proto.Block {
+	b := childBlock(parent)
+	b.Transactions = txs
+	types.SignBlock(crypto.GeneratePrivateKey(), b)
+	return b
+}
+
+func TestStorageTransaction(t *testing.T) {
+	var (
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		genesis = createGenesisBlock()
+		privKey = crypto.GeneratePrivateKey()
+		tx1     = signedTx(privKey)
+		tx2     = signedTx(privKey)
+		a1      = blockWithTxs(genesis, tx1, tx2)
+		b1      = blockWithTxs(genesis, tx1)
+		b2      = blockWithTxs(b1)
+	)
+	defer storage.Close()
+
+	_, err := storage.Transaction(types.HashTransaction(tx1))
+	assert.Error(t, err)
+
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{a1}}, nil))
+	for _, hash := range [][]byte{types.HashTransaction(tx2), types.HashTransactionNoSigPuK(tx2)} {
+		res, err := storage.Transaction(hash)
+		require.Nil(t, err)
+		assert.Equal(t, types.HashBlock(a1), res.BlockHash)
+		assert.Equal(t, int32(1), res.BlockHeight)
+		assert.Equal(t, int32(1), res.TxIndex)
+		assert.Equal(t, types.HashTransaction(tx2), types.HashTransaction(res.Transaction))
+	}
+
+	// a reorg moves the transactions of the new branch and drops the others
+	require.Nil(t, storage.CommitReorg(&Reorg{Removed: []*proto.Block{a1}, Added: []*proto.Block{b1, b2}}, nil))
+	res, err := storage.Transaction(types.HashTransaction(tx1))
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(b1), res.BlockHash)
+	assert.Equal(t, int32(0), res.TxIndex)
+	_, err = storage.Transaction(types.HashTransactionNoSigPuK(tx2))
+	assert.Error(t, err)
+}
+
+func TestStorageReindex(t *testing.T) {
+	var (
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		tx      = signedTx(crypto.GeneratePrivateKey())
+		b1      = blockWithTxs(createGenesisBlock(), tx)
+		hash    = types.HashTransaction(tx)
+	)
+	defer storage.Close()
+
+	// a database written before the tx index existed
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b1}}, nil))
+	for _, key := range txKeys(tx) {
+		require.Nil(t, storage.db.Delete(txIndexNamespace, key))
+	}
+	_, err := storage.Transaction(hash)
+	require.Error(t, err)
+
+	require.Nil(t, storage.reindex())
+	res, err := storage.Transaction(hash)
+	require.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(types.HashBlock(b1)), hex.EncodeToString(res.BlockHash))
+}
+
+func TestStorageTransactionsByAddress(t *testing.T) {
+	var (
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		owner   = crypto.GeneratePrivateKey()
+		other   = crypto.GeneratePrivateKey()
+		address = owner.Public().Address().Bytes()
+		txs     = []*proto.Transaction{signedTx(owner), signedTx(owner), signedTx(owner), signedTx(owner), signedTx(owner)}
+		b1      = blockWithTxs(createGenesisBlock(), txs[0], signedTx(other), txs[1])
+		b2      = blockWithTxs(b1, txs[2])
+		b3      = blockWithTxs(b2, txs[3], txs[4])
+	)
+	defer storage.Close()
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b1, b2, b3}}, nil))
+
+	// pages of two come oldest first until the token runs out
+	var (
+		got   []*proto.TxSearchResult
+		token []byte
+		pages int
+	)
+	for {
+		page, next, err := storage.TransactionsByAddress(address, 2, token)
+		require.Nil(t, err)
+		assert.LessOrEqual(t, len(page), 2)
+		got = append(got, page...)
+		pages++
+		if next == nil {
+			break
+		}
+		token = next
+	}
+	assert.Equal(t, 3, pages)
+	require.Len(t, got, len(txs))
+	for i, tx := range txs {
+		assert.Equal(t, types.HashTransaction(tx), types.HashTransaction(got[i].Transaction))
+	}
+
+	_, _, err := storage.TransactionsByAddress(other.Public().Address().Bytes(), 2, token)
+	assert.ErrorIs(t, err, ErrBadPageToken)
+
+	// blocks leaving the chain leave the listing
+	require.Nil(t, storage.CommitReorg(&Reorg{Removed: []*proto.Block{b3}}, nil))
+	page, next, err := storage.TransactionsByAddress(address, 10, nil)
+	require.Nil(t, err)
+	assert.Nil(t, next)
+	assert.Len(t, page, 3)
+}
+
+func payloadTx(privKey *crypto.PrivateKey, payload string) *proto.Transaction {
+	tx := signedTx(privKey)
+	tx.Outputs[0].Payload = []byte(payload)
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+	return tx
+}
+
+func TestStorageTransactionsByPayload(t *testing.T) {
+	var (
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		privKey = crypto.GeneratePrivateKey()
+		first   = payloadTx(privKey, `{"metadata": "sims_a"}`)
+		again   = payloadTx(privKey, `{"metadata":"sims_a", "seq": 2}`)
+		number  = payloadTx(privKey, `{"metadata": {"n": 42}}`)
+		plain   = payloadTx(privKey, `not json`)
+		b1      = blockWithTxs(createGenesisBlock(), first, plain)
+		b2      = blockWithTxs(b1, again, number)
+	)
+	storage.payloadFields = []string{"metadata"}
+	defer storage.Close()
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b1, b2}}, nil))
+
+	// an exact payload finds only the same bytes
+	page, next, err := storage.TransactionsByPayload([]byte(`{"metadata": "sims_a"}`), 10, nil)
+	require.Nil(t, err)
+	assert.Nil(t, next)
+	require.Len(t, page, 1)
+	assert.Equal(t, types.HashTransaction(first), types.HashTransaction(page[0].Transaction))
+	page, _, err = storage.TransactionsByPayload([]byte("not json"), 10, nil)
+	require.Nil(t, err)
+	assert.Len(t, page, 1)
+
+	// a field value finds every payload that carries it, however it is written
+	page, _, err = storage.TransactionsByField("metadata", "sims_a", 10, nil)
+	require.Nil(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, int32(1), page[0].BlockHeight)
+	assert.Equal(t, int32(2), page[1].BlockHeight)
+	page, _, err = storage.TransactionsByField("metadata", `{"n":42}`, 10, nil)
+	require.Nil(t, err)
+	assert.Len(t, page, 1)
+
+	_, _, err = storage.TransactionsByField("seq", "2", 10, nil)
+	assert.ErrorIs(t, err, ErrFieldNotIndexed)
+
+	// other fields are indexed once the chain is reindexed
+	storage.payloadFields = []string{"seq"}
+	require.Nil(t, storage.reindex())
+	page, _, err = storage.TransactionsByField("seq", "2", 10, nil)
+	require.Nil(t, err)
+	assert.Len(t, page, 1)
+	n, err := storage.db.Len(badgerNamespace(fieldIndexNamespace))
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+}
@@ -1,8 +1,12 @@
 package node
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"time"
@@ -10,13 +14,14 @@ import (
 	"github.com/janrockdev/darkblock/consensus"
 	"github.com/janrockdev/darkblock/crypto"
 	"github.com/janrockdev/darkblock/proto"
-	"github.com/janrockdev/darkblock/services"
 	"github.com/janrockdev/darkblock/types"
 	"github.com/janrockdev/darkblock/util"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -28,6 +33,15 @@ var (
 	reset             = "\x1b[0m"
 )
 
+// errNodeStopped is returned for blocks that arrive while the node shuts down.
+var errNodeStopped = errors.New("node stopped")
+
+// The page sizes of the listing RPCs.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
 // Mempool struct.
 type Mempool struct {
 	lock sync.RWMutex
@@ -91,9 +105,14 @@ func (pool *Mempool) Add(tx *proto.Transaction) bool {
 
 // ServerConfig struct.
 type ServerConfig struct {
-	Version    string
-	ListenAddr string
-	PrivateKey *crypto.PrivateKey
+	Version       string
+	ListenAddr    string
+	PrivateKey    *crypto.PrivateKey
+	Validators    []*crypto.PublicKey
+	WALPath       string // consensus write-ahead log, no log is kept when empty
+	Engine        string // name of the consensus engine, see consensus.Engines
+	Confirmations int    // blocks on top of a block that make it final, for engines that do not certify blocks
+	SyncMode      string // SyncHeaders for headers-first sync, blocks are streamed otherwise
 }
 
 // Node struct.
@@ -103,12 +122,24 @@ type Node struct {
 
 	peerLock sync.RWMutex
 	peers    map[proto.NodeClient]*proto.Version
+	queues   map[proto.NodeClient]*peerQueue // outbound messages of each peer
 	mempool  *Mempool
+	evidence *EvidencePool
 	chain    *Chain
+	certs    CertificateStorer
+	storage  *Storage // open for the lifetime of the node
+	server   *grpc.Server
 	//cache       services.DB
 	dialedAddrs map[string]string // Comment: This map is used to keep track of the addresses that have been dialed by this node
 
 	ConsensusEngine consensus.Consensus //consensus.Consensus
+	lastSigned      *proto.Block        // the last block this validator signed and proposed
+
+	applyLock sync.Mutex // serializes appending blocks to the chain and the block store
+	syncLock  sync.Mutex // one sync at a time
+	quit      chan struct{}
+	loops     sync.WaitGroup // validator, commit, evidence and outbox loops
+	stopped   bool           // set under applyLock once the storage is closed
 
 	proto.UnimplementedNodeServer
 }
@@ -117,18 +148,69 @@ type Node struct {
 func NewNode(cfg ServerConfig, bootstrapNodes []string) *Node {
 	logger := util.Logger
 
-	rpbft := consensus.NewPBFTPoA(bootstrapNodes)
+	// Badger allows one connection per process, all stores share it
+	cfgFile := util.LoadConfig()
+	storage, err := OpenStorage(cfgFile.BADGER.DataDir, cfgFile.BADGER.PayloadFields, openSinks(cfgFile)...)
+	if err != nil {
+		logger.Fatal().Msgf("failed to open storage: %s", err)
+	}
 
-	return &Node{
+	n := &Node{
 		peers:       make(map[proto.NodeClient]*proto.Version),
+		queues:      make(map[proto.NodeClient]*peerQueue),
 		dialedAddrs: make(map[string]string), // Comment: Initialize the map
 		Logger:      &logger,
 		mempool:     NewMempool(),
-		chain:       NewChain(NewMemoryBlockStore(), NewMemoryTXStore()),
+		evidence:    NewEvidencePool(),
+		chain:       NewChain(storage.blocks, storage.txs),
+		certs:       NewMemoryCertificateStore(),
+		storage:     storage,
+		quit:        make(chan struct{}),
 		//cache:           &services.BadgerDB{}, // <---- review
-		ConsensusEngine: rpbft, // <---- review
-		ServerConfig:    cfg,
+		ServerConfig: cfg,
+	}
+	// the chain follows the on-chain validator set of the configured validators
+	validators := cfg.Validators
+	if len(validators) == 0 && cfg.PrivateKey != nil {
+		validators = []*crypto.PublicKey{cfg.PrivateKey.Public()}
 	}
+	validatorSet := loadValidatorSet(storage, validators)
+	if len(validatorSet.Validators) > 0 {
+		n.chain.SetValidatorSet(validatorSet)
+	}
+	// engines without commit certificates finalize blocks by depth, raft
+	// included, see the consensus section of config.yaml
+	if cmp.Or(cfg.Engine, consensus.DefaultEngine) != "pbft" {
+		n.chain.SetConfirmations(cfg.Confirmations)
+	}
+	n.restoreFinality()
+
+	if cfg.PrivateKey != nil {
+		var wal consensus.WAL
+		if cfg.WALPath != "" {
+			fileWAL, err := consensus.OpenFileWAL(cfg.WALPath)
+			if err != nil {
+				logger.Fatal().Msgf("failed to open consensus wal [%s]: %s", cfg.WALPath, err)
+			}
+			wal = fileWAL
+		}
+		engine, err := consensus.New(cfg.Engine, consensus.Options{
+			Validators:   cfg.Validators,
+			PrivateKey:   cfg.PrivateKey,
+			Transport:    n,
+			WAL:          wal,
+			SlotDuration: blockTime,
+			Blocks:       storage,
+			BlockRules:   n.checkProposal,
+			ValidatorSet: validatorSet,
+		})
+		if err != nil {
+			logger.Fatal().Msgf("failed to create consensus engine: %s", err)
+		}
+		n.ConsensusEngine = engine
+	}
+
+	return n
 }
 
 // Start starts the node.
@@ -144,31 +226,198 @@ func (n *Node) Start(listenAddr string, bootstrapNodes []string) error {
 		return err
 	}
 	proto.RegisterNodeServer(grpcServer, n)
+	n.server = grpcServer
 
 	// Comment: Initialize cache
 	//n.cache = services.NewBadgerDB(db_dir)
 
 	n.Logger.Info().Msgf("node running on port: [%s]", n.ListenAddr)
 
-	if len(bootstrapNodes) > 0 {
-		go n.bootstrapNetwork(bootstrapNodes)
+	// the secondary stores and the webhooks are fed in the background, block
+	// production never waits for them
+	n.runLoop(func() { n.storage.outbox.Run(n.quit) })
+	n.runLoop(func() { n.storage.webhooks.Run(n.quit) })
+
+	// the node catches up with the chain of its peers before it takes part in consensus
+	go func() {
+		if len(bootstrapNodes) > 0 {
+			n.bootstrapNetwork(bootstrapNodes)
+		}
+
+		if n.PrivateKey != nil {
+			n.runLoop(n.validatorLoop)
+			n.runLoop(n.commitLoop)
+			go n.ConsensusEngine.Start()
+			if reporter, ok := n.ConsensusEngine.(consensus.EvidenceReporter); ok {
+				n.runLoop(func() { n.evidenceLoop(reporter) })
+			}
+		}
+	}()
+
+	return grpcServer.Serve(ln)
+}
+
+// runLoop starts a loop that Stop waits for.
+func (n *Node) runLoop(loop func()) {
+	n.loops.Add(1)
+	go func() {
+		defer n.loops.Done()
+		loop()
+	}()
+}
+
+// Stop shuts the node down: the server and the loops stop, the block being
+// applied is finished and the storage is closed.
+func (n *Node) Stop() error {
+	close(n.quit)
+	if n.server != nil {
+		n.server.Stop()
+	}
+	if n.ConsensusEngine != nil {
+		n.ConsensusEngine.Stop()
 	}
+	n.loops.Wait()
 
-	if n.PrivateKey != nil {
-		go n.validatorLoop()
-		go n.ConsensusEngine.Start()
+	n.peerLock.Lock()
+	for _, q := range n.queues {
+		q.close()
 	}
+	n.peerLock.Unlock()
 
-	return grpcServer.Serve(ln)
+	n.applyLock.Lock()
+	defer n.applyLock.Unlock()
+	n.stopped = true
+	n.Logger.Info().Msgf("node on port [%s] stopped", n.ListenAddr)
+
+	return n.storage.Close()
+}
+
+// GetTransaction returns an included transaction by hash, with the block that
+// contains it.
+func (n *Node) GetTransaction(ctx context.Context, v *proto.TxSearch) (*proto.TxSearchResult, error) {
+	if len(v.Hash) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "transaction hash required")
+	}
+	res, err := n.storage.Transaction(v.Hash)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return res, nil
+}
+
+// ListTransactionsByAddress returns a page of the included transactions sent to
+// or by an address.
+func (n *Node) ListTransactionsByAddress(ctx context.Context, v *proto.AddressSearch) (*proto.AddressSearchResult, error) {
+	if len(v.Address) != crypto.AddressLen {
+		return nil, status.Errorf(codes.InvalidArgument, "address of [%d] bytes", len(v.Address))
+	}
+	txs, next, err := n.storage.TransactionsByAddress(v.Address, pageLimit(v.Limit), v.PageToken)
+	if err != nil {
+		return nil, listStatus(err)
+	}
+	return &proto.AddressSearchResult{Transactions: txs, NextPageToken: next}, nil
+}
+
+// SearchByPayload returns a page of the included transactions with a payload,
+// or with a value of an indexed field of JSON payloads.
+func (n *Node) SearchByPayload(ctx context.Context, v *proto.PayloadSearch) (*proto.PayloadSearchResult, error) {
+	var (
+		txs  []*proto.TxSearchResult
+		next []byte
+		err  error
+	)
+	switch {
+	case len(v.Payload) > 0 && v.Field == "":
+		txs, next, err = n.storage.TransactionsByPayload(v.Payload, pageLimit(v.Limit), v.PageToken)
+	case len(v.Payload) == 0 && v.Field != "":
+		txs, next, err = n.storage.TransactionsByField(v.Field, v.Value, pageLimit(v.Limit), v.PageToken)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "either a payload or a field is required")
+	}
+	if err != nil {
+		return nil, listStatus(err)
+	}
+	return &proto.PayloadSearchResult{Transactions: txs, NextPageToken: next}, nil
+}
+
+// Subscribe registers a webhook subscription, the result carries its id and
+// the secret signing its notifications.
+func (n *Node) Subscribe(ctx context.Context, v *proto.Subscription) (*proto.Subscription, error) {
+	sub, err := n.storage.webhooks.Subscribe(v)
+	if errors.Is(err, ErrInvalidSubscription) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return sub, nil
 }
 
-// GetTransaction returns a transaction by hash.
+// Unsubscribe removes a webhook subscription.
+func (n *Node) Unsubscribe(ctx context.Context, v *proto.Subscription) (*proto.Ack, error) {
+	if err := n.storage.webhooks.Unsubscribe(v.Id); err != nil {
+		return nil, listStatus(err)
+	}
+	return &proto.Ack{}, nil
+}
+
+// ListDeadLetters returns a page of the notifications of a webhook
+// subscription that were given up.
+func (n *Node) ListDeadLetters(ctx context.Context, v *proto.DeadLetterSearch) (*proto.DeadLetterResult, error) {
+	letters, next, err := n.storage.webhooks.DeadLetters(v.SubscriptionId, pageLimit(v.Limit), v.PageToken)
+	if err != nil {
+		return nil, listStatus(err)
+	}
+	return &proto.DeadLetterResult{DeadLetters: letters, NextPageToken: next}, nil
+}
+
+// pageLimit bounds the page size asked for by a listing.
+func pageLimit(limit int32) int {
+	switch {
+	case limit <= 0:
+		return defaultPageSize
+	case limit > maxPageSize:
+		return maxPageSize
+	}
+	return int(limit)
+}
+
+// listStatus turns the error of a listing into a gRPC status.
+func listStatus(err error) error {
+	switch {
+	case errors.Is(err, ErrBadPageToken):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrFieldNotIndexed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrUnknownSubscription):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// GetBlock returns a block by height.
 func (n *Node) GetBlock(ctx context.Context, v *proto.BlockSearch) (*proto.BlockSearchResult, error) {
 	block, err := n.chain.GetBlockByHeight(int(v.BlockHeight))
 	if err != nil {
 		return nil, err
 	}
-	return &proto.BlockSearchResult{Block: block}, nil
+	return &proto.BlockSearchResult{Block: block, Certificate: n.getCommitCertificate(types.HashBlock(block))}, nil
+}
+
+// getCommitCertificate returns the commit certificate of a block, or nil if the block has none.
+func (n *Node) getCommitCertificate(hash []byte) *proto.CommitCertificate {
+	hashHex := hex.EncodeToString(hash)
+	if cert, err := n.certs.Get(hashHex); err == nil {
+		return cert
+	}
+
+	// blocks committed before a restart only have their certificate on disk
+	cert, err := n.storage.Certificate(hash)
+	if err != nil {
+		return nil
+	}
+	n.certs.Put(cert)
+
+	return cert
 }
 
 // Handshake performs a handshake with a remote node.
@@ -193,6 +442,17 @@ func (n *Node) HandleTransaction(ctx context.Context, tx *proto.Transaction) (*p
 		return nil, nil
 	}
 
+	// governance without the approval of the current validators never makes it into a block
+	if tx.Governance != nil {
+		set := n.chain.ValidatorSet()
+		if set == nil {
+			return nil, errors.New("node does not follow validator set governance")
+		}
+		if err := types.VerifyGovernance(set, tx.Governance, set.Height+1); err != nil {
+			return nil, err
+		}
+	}
+
 	if n.mempool.Add(tx) {
 		baseTx := types.CopyTransaction(tx)
 		baseTx.Inputs[0].Signature = nil
@@ -201,11 +461,7 @@ func (n *Node) HandleTransaction(ctx context.Context, tx *proto.Transaction) (*p
 		n.Logger.Debug().Msgf("received transaction from [%s] [%s] with hash [%s%s%s] sign [%s] pk [%s]",
 			peer.Addr, n.ListenAddr, red, hash[:3], reset, hex.EncodeToString(tx.Inputs[0].Signature)[:3], hex.EncodeToString(tx.Inputs[0].PublicKey)[:3])
 		n.Logger.Debug().Msgf("payload: [%s]", string(tx.Outputs[0].Payload))
-		go func() {
-			if err := n.broadcast(tx); err != nil {
-				n.Logger.Error().Msgf("failed to broadcast transaction [%s]", err)
-			}
-		}()
+		n.broadcast(tx)
 	}
 
 	return &proto.Ack{}, nil
@@ -213,6 +469,21 @@ func (n *Node) HandleTransaction(ctx context.Context, tx *proto.Transaction) (*p
 
 // HandleBlock handles incoming block.
 func (n *Node) HandleBlock(ctx context.Context, bk *proto.Block) (*proto.Ack, error) {
+	return n.receiveBlock(bk, nil)
+}
+
+// HandleCertifiedBlock handles a block finalized by the validators. The
+// certificate is verified against the validator set of the chain before the
+// block is finalized, one that does not verify is dropped.
+func (n *Node) HandleCertifiedBlock(ctx context.Context, res *proto.BlockSearchResult) (*proto.Ack, error) {
+	if res.GetBlock().GetHeader() == nil {
+		return nil, status.Error(codes.InvalidArgument, "block required")
+	}
+	return n.receiveBlock(res.Block, res.Certificate)
+}
+
+// receiveBlock applies a block pushed by a peer.
+func (n *Node) receiveBlock(bk *proto.Block, cert *proto.CommitCertificate) (*proto.Ack, error) {
 	//peer, _ := peer.FromContext(ctx)
 	hash := hex.EncodeToString(types.HashBlock(bk))
 	height := bk.Header.Height
@@ -221,45 +492,112 @@ func (n *Node) HandleBlock(ctx context.Context, bk *proto.Block) (*proto.Ack, er
 	n.Logger.Info().Msgf("received block [%s] with height [%d] and [%d] transaction/s",
 		hash[:3], height, size)
 
-	n.chain.AddBlock(bk)
+	// a block past the tip leaves a gap, the missing blocks come from the peers
+	if int(height) > n.storage.Height()+1 {
+		go n.syncPeers()
+		return &proto.Ack{}, nil
+	}
+
+	// validators apply finalized blocks through the consensus engine, an already known block only adds its certificate
+	if err := n.applyBlock(bk, cert); err != nil {
+		// the block waits in the orphan pool until its parent is synced
+		if errors.Is(err, ErrOrphanBlock) {
+			go n.syncPeers()
+			return &proto.Ack{}, nil
+		}
+		n.Logger.Warn().Msgf("rejected block [%s] with height [%d]: [%s]", hash[:3], height, err)
+		return nil, blockStatus(err)
+	}
 
 	return &proto.Ack{}, nil
 }
 
-func initBlock(chain *Chain) *proto.Block {
-	var (
-		hash       []byte
-		prevHash   []byte
-		prevHeight int64
-		db_dir     = util.LoadConfig().BADGER.DataDir
-	)
+// HandleConsensusMessage routes an incoming consensus message to the consensus engine.
+func (n *Node) HandleConsensusMessage(ctx context.Context, msg *proto.ConsensusMessage) (*proto.Ack, error) {
+	// only validators run the consensus engine
+	if n.PrivateKey == nil {
+		return &proto.Ack{}, nil
+	}
 
-	if services.FolderExists(db_dir) {
-		DB, err := services.ConnectBadgerDB(db_dir)
-		if err != nil {
-			logger.Error().Msgf("failed to connect to badgerDB: [%s]", err)
-		}
-		_, prevHeight, hash, err = DB.GetLatestRecord()
-		if err != nil {
-			logger.Error().Msgf("failed to get latest block index: [%s]", err)
+	n.ConsensusEngine.OnReceiveMessage(consensus.MessageFromProto(msg))
+
+	return &proto.Ack{}, nil
+}
+
+// loadValidatorSet returns the validator set persisted with the last committed
+// block, or the set of the configured validators on a new chain.
+func loadValidatorSet(storage *Storage, validators []*crypto.PublicKey) *proto.ValidatorSet {
+	set := types.NewValidatorSet(validators)
+	persisted, err := storage.ValidatorSet()
+	if err != nil {
+		logger.Error().Msgf("failed to load validator set: [%s]", err)
+		return set
+	}
+	if len(persisted.GetValidators()) == 0 {
+		return set
+	}
+	logger.Info().Msgf("loaded validator set of height [%d] with [%d] validators", persisted.Height, len(persisted.Validators))
+	return persisted
+}
+
+// HandleRaftMessage passes a raft message to the consensus engine, engines other than raft ignore it.
+func (n *Node) HandleRaftMessage(ctx context.Context, msg *proto.RaftMessage) (*proto.Ack, error) {
+	if n.PrivateKey == nil {
+		return &proto.Ack{}, nil
+	}
+	if engine, ok := n.ConsensusEngine.(consensus.RaftReceiver); ok {
+		engine.OnReceiveRaftMessage(msg)
+	}
+
+	return &proto.Ack{}, nil
+}
+
+// SendRaftMessage implements consensus.RaftTransport. A message goes to the
+// peers that announced its addressee as their validator key, one without an
+// addressee goes to all peers.
+func (n *Node) SendRaftMessage(msg *proto.RaftMessage) error {
+	if msg.To == "" {
+		n.broadcast(msg)
+		return nil
+	}
+
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+	sent := false
+	for peer, v := range n.peers {
+		if v.Validator != msg.To {
+			continue
 		}
-		prevHash = []byte(hash)
-		DB.Close()
-	} else {
-		var err error
-		prevBlock, err := chain.GetBlockByHeight(chain.Height())
-		prevHash = types.HashBlock(prevBlock)
-		if err != nil {
-			logger.Panic().Msgf("failed to get previous block height: [%s]", err)
+		sent = true
+		if !n.queues[peer].push(msg) {
+			n.Logger.Warn().Msgf("dropping [%s] raft message for peer [%s], its queue is full", msg.Type, v.ListenAddr)
 		}
 	}
+	if !sent {
+		return fmt.Errorf("no peer of validator [%s]", msg.To[:8])
+	}
+	return nil
+}
+
+// BroadcastConsensusMessage sends a consensus message to all connected peers.
+func (n *Node) BroadcastConsensusMessage(msg consensus.ConsensusMessage) error {
+	n.broadcast(msg.ToProto())
+	return nil
+}
+
+func initBlock(chain *Chain) *proto.Block {
+	// the chain is rebuilt from the block store, its tip is the last committed block
+	prevBlock := chain.Tip()
+
+	// the timestamp never goes back behind the parent, whatever the local clock says
+	timestamp := max(time.Now().UnixNano(), prevBlock.Header.Timestamp)
 
 	header := &proto.Header{
-		Version:   1,                     // from config file
-		Height:    int32(prevHeight) + 1, // int32(chain.Height() + 1),  // current size of blockStore + 1
-		PrevHash:  prevHash,              // types.HashBlock(prevBlock), // previous full block hash
-		RootHash:  nil,                   // merkle root hash, to be calculated
-		Timestamp: time.Now().UnixNano(),
+		Version:   1,                           // from config file
+		Height:    prevBlock.Header.Height + 1, // int32(chain.Height() + 1),  // current size of blockStore + 1
+		PrevHash:  types.HashBlock(prevBlock),  // previous full block hash
+		RootHash:  nil,                         // merkle root hash, to be calculated
+		Timestamp: timestamp,
 	}
 
 	return &proto.Block{
@@ -274,27 +612,31 @@ func initBlock(chain *Chain) *proto.Block {
 func (n *Node) validatorLoop() {
 	n.Logger.Debug().Msgf("validator loop started with blocktime [%s] - waiting for transactions...", blockTime)
 	ticker := time.NewTicker(blockTime)
-	//refactoring
 	var (
-		privKey   = crypto.NewPrivateKeyFromSeedStr(util.LoadConfig().KEYS.GodSeed) // <---- this has to be refactored
-		recipient = []byte{}
-		db_dir    = util.LoadConfig().BADGER.DataDir // <---- this has to be refactored
+		privKey   = n.PrivateKey
+		recipient = privKey.Public().Address().Bytes()
 	)
 
-	privKey, err := crypto.LoadPrivateKeyFromFile("private_key.txt")
+	// the last signed block survives restarts, a validator never signs a second block at its height
+	lastSigned, err := n.storage.LastSigned()
 	if err != nil {
-		logger.Fatal().Msgf("failed to load private key: %s", err)
+		logger.Fatal().Msgf("failed to load the last signed block: %s", err)
 	}
-	recipient = privKey.Public().Address().Bytes()
+	n.lastSigned = lastSigned
 
+	defer ticker.Stop()
 	for {
-		<-ticker.C
+		select {
+		case <-n.quit:
+			return
+		case <-ticker.C:
+		}
 
 		txx := n.mempool.Clear() // Load all transactions to txx clean the mempool
 		//n.Logger.Debug().Msgf("memPool [%d] txStore [%d] blockStore [%d]", len(txx), n.chain.txStore.Size(), n.chain.blockStore.Size())
 
-		// check if transactions are available
-		if len(txx) > 0 {
+		// check if transactions or evidence are available
+		if len(txx) > 0 || n.evidence.Len() > 0 {
 			// create a new block
 			block := initBlock(n.chain)
 
@@ -369,10 +711,11 @@ func (n *Node) validatorLoop() {
 					},
 				}
 				tx := &proto.Transaction{
-					Version:   1,
-					Timestamp: tx.Timestamp,
-					Inputs:    inputs,
-					Outputs:   outputs,
+					Version:    1,
+					Timestamp:  tx.Timestamp,
+					Inputs:     inputs,
+					Outputs:    outputs,
+					Governance: tx.Governance,
 				}
 
 				originalTx := hex.EncodeToString(types.HashTransaction(baseTx))
@@ -395,23 +738,48 @@ func (n *Node) validatorLoop() {
 			}
 
 			// build merkle tree
-			tree, err := types.GetMerkleTree(block)
-			if err != nil {
-				logger.Panic().Msgf("failed to build merkle tree: [%s]", err)
+			if len(block.Transactions) > 0 {
+				tree, err := types.GetMerkleTree(block)
+				if err != nil {
+					logger.Panic().Msgf("failed to build merkle tree: [%s]", err)
+				}
+				block.Header.RootHash = tree.MerkleRoot()
 			}
-			block.Header.RootHash = tree.MerkleRoot()
+
+			// include the pending evidence, the header commits to it
+			block.Evidence = n.evidence.All()
+			block.Header.EvidenceHash = types.HashEvidenceList(block.Evidence)
 
 			logger.Debug().Msgf("(6) block template [%s] built with [%d] transactions, height [%d], prevHash [%s] and merkle [%s]",
 				blockTemplateHash[:3],
 				len(block.Transactions),
 				block.GetHeader().Height,
 				hex.EncodeToString(block.GetHeader().PrevHash)[:3],
-				hex.EncodeToString(block.GetHeader().RootHash)[:3])
+				hex.EncodeToString(block.GetHeader().RootHash))
 
 			//logger.Debug().Msgf("pubKey: [%s]", hex.EncodeToString(privKey.Public().Bytes()))
 
-			types.SignBlock(privKey, block)
-			logger.Debug().Msgf("(7) new block [%s] (from template [%s]) has been created and signed", hex.EncodeToString(types.HashBlock(block))[:3], blockTemplateHash[:3])
+			// a validator signs a single block per height, a block it already proposed is proposed again
+			var (
+				previous   = n.lastSigned
+				reproposed = previous != nil && previous.Header.Height == block.Header.Height
+			)
+			if reproposed {
+				logger.Debug().Msgf("(7) block height [%d] already signed, proposing block [%s] again", block.Header.Height, hex.EncodeToString(types.HashBlock(n.lastSigned))[:3])
+				block = n.lastSigned
+			} else {
+				types.SignBlock(privKey, block)
+				logger.Debug().Msgf("(7) new block [%s] (from template [%s]) has been created and signed", hex.EncodeToString(types.HashBlock(block))[:3], blockTemplateHash[:3])
+				// the signed block is durable before it leaves the node
+				if err := n.storage.PutLastSigned(block); err != nil {
+					n.Logger.Error().Msgf("failed to persist signed block [%s], not proposing it: [%s]", hex.EncodeToString(types.HashBlock(block))[:3], err)
+					for _, tx := range txx {
+						n.mempool.Add(tx)
+					}
+					continue
+				}
+				n.lastSigned = block
+			}
 
 			// // add validation here (remove chain.AddBlock(block)) <---- this has to be refactored
 			// ver := types.VerifyBlock(block)
@@ -422,76 +790,216 @@ func (n *Node) validatorLoop() {
 
 			n.chain.txStore.Clear()
 
-			var lastBlockHeight int64 = 0
-
-			// BadgerDB
-			bdb, err := services.ConnectBadgerDB(db_dir)
-			if err != nil {
-				logger.Error().Msgf("failed to connect to badgerDB: [%s]", err)
-			}
-			if services.FolderExists(db_dir) {
-				_, lastBlockHeight, _, _ = bdb.GetLatestRecord() //ignore error
+			// the block is appended to the chain only once consensus finalizes it (see commitLoop)
+			n.Logger.Debug().Msgf("(8) proposing block [%s] with [%d] transactions to consensus", hex.EncodeToString(types.HashBlock(block))[:3], len(block.Transactions))
+			err := n.ConsensusEngine.ProposeBlock(block)
+			if err == nil && reproposed {
+				// the block proposed again does not carry the new transactions
+				for _, tx := range txx {
+					n.mempool.Add(tx)
+				}
+			} else if err != nil && !reproposed {
+				// the block never left the node, another one may be signed at its height
+				n.lastSigned = previous
 			}
-			bdb.Set([]byte("blockStore"), []byte(hex.EncodeToString(types.HashBlock(block))), lastBlockHeight+1, types.BlockBytes(block))
-			keys, err := bdb.Len([]byte("blockStore"))
-			if err != nil {
-				logger.Error().Msgf("badger access error (Len)")
+			if errors.Is(err, consensus.ErrNotProposer) {
+				n.Logger.Debug().Msgf("not the proposer of block [%s], keeping [%d] transactions", hex.EncodeToString(types.HashBlock(block))[:3], len(txx))
+				for _, tx := range txx {
+					n.mempool.Add(tx)
+				}
+			} else if err != nil {
+				n.Logger.Error().Msgf("failed to propose block [%s]: [%s]", hex.EncodeToString(types.HashBlock(block))[:3], err)
+				// the block was not taken, its transactions go into the next one
+				for _, tx := range txx {
+					n.mempool.Add(tx)
+				}
 			}
+		}
+	}
+}
 
-			// Couchbase
-			cs, err := services.NewCouchbaseService("couchbase://localhost", "Administrator", "password", "blocks", "transactions")
-			if err != nil {
-				logger.Error().Msgf("failed to create Couchbase service: %v", err)
+// commitLoop applies the blocks finalized by the consensus engine.
+func (n *Node) commitLoop() {
+	for {
+		select {
+		case <-n.quit:
+			return
+		case commit, ok := <-n.ConsensusEngine.Committed():
+			if !ok {
+				return
 			}
-
-			err = cs.StoreBlock(block)
-			if err != nil {
-				logger.Error().Msgf("failed to store block: %v", err)
+			if err := n.commitBlock(commit.Block, commit.Certificate); err != nil {
+				n.Logger.Error().Msgf("failed to commit block [%s] finalized in view [%d]: [%s]",
+					hex.EncodeToString(types.HashBlock(commit.Block))[:3], commit.View, err)
 			}
-			cs.Close()
+		}
+	}
+}
 
-			// probably the place to plug consensus
-			n.Logger.Debug().Msgf("(8) proposing block [%s] with [%d] transactions to consensus", hex.EncodeToString(types.HashBlock(block))[:3], len(block.Transactions))
-			n.ConsensusEngine.ProposeBlock(block)
+// commitBlock applies a finalized block, the proposer forwards it to its peers.
+func (n *Node) commitBlock(block *proto.Block, cert *proto.CommitCertificate) error {
+	if err := n.applyBlock(block, cert); err != nil {
+		return err
+	}
 
-			// validate and append to chain (header + merkle + signature, no transactions)
-			n.chain.AddBlock(block)
+	// the proposer forwards the finalized block to the non-validator peers,
+	// with the certificate that makes it final for them as well
+	if n.PrivateKey != nil && bytes.Equal(block.PublicKey, n.PrivateKey.Public().Bytes()) {
+		if cert != nil {
+			n.broadcast(&proto.BlockSearchResult{Block: block, Certificate: cert})
+		} else {
+			n.broadcast(block)
+		}
+	}
 
-			n.Logger.Info().Msgf("(10) block height [%d] blockStore(M) size [%d] blockStore(P) size [%d] txStore size [%d] headers [%d]",
-				n.chain.Height(), n.chain.blockStore.Size(), keys, n.chain.txStore.Size(), n.chain.headers.Height())
+	return nil
+}
 
-			// broadcast the block
-			n.broadcast(block)
+// applyBlock validates and appends a block to the chain and persists it
+// together with its commit certificate. A block that is already part of the
+// chain only adds its certificate.
+func (n *Node) applyBlock(block *proto.Block, cert *proto.CommitCertificate) error {
+	n.applyLock.Lock()
+	defer n.applyLock.Unlock()
+	if n.stopped {
+		return errNodeStopped
+	}
+	_, err := n.chain.GetBlockByHash(types.HashBlock(block))
+	known := err == nil
+	if known && cert == nil {
+		return nil
+	}
 
-			bdb.Close()
+	// validate and insert into the block tree (header + merkle + signature, no transactions)
+	reorg, err := n.chain.InsertBlock(block, cert)
+	if errors.Is(err, ErrBadCertificate) {
+		// a certificate from a peer is only trusted once it is verified, the
+		// block is taken without it
+		n.Logger.Warn().Msgf("dropping commit certificate of block [%s] height [%d]: [%s]", hex.EncodeToString(types.HashBlock(block))[:3], block.Header.Height, err)
+		if known {
+			return nil
 		}
+		cert = nil
+		reorg, err = n.chain.InsertBlock(block, nil)
+	}
+	if err != nil {
+		var equivocation *EquivocationError
+		if errors.As(err, &equivocation) {
+			n.addEvidence(equivocation.Evidence)
+		}
+		return err
+	}
+	if known && len(reorg.Added) == 0 {
+		if err := n.storeCertificate(cert); err != nil {
+			return err
+		}
+		if len(reorg.Finalized) == 0 {
+			return nil
+		}
+		return n.storage.CommitReorg(reorg, nil)
 	}
-}
 
-// Broadcast sends a message to all connected peers.
-func (n *Node) broadcast(msg any) error {
-	for peer := range n.peers {
-		switch v := msg.(type) {
-		case *proto.Transaction:
-			_, err := peer.HandleTransaction(context.Background(), v)
-			if err != nil {
-				logger.Warn().Msgf("removing peer [%s] from list", peer)
-				n.deletePeer(peer)
-				return err
-			}
-		case *proto.Block:
-			_, err := peer.HandleBlock(context.Background(), v)
-			if err != nil {
-				//logger.Warn().Msgf("removing peer [%s] from list", peer)
-				//n.deletePeer(peer)
-				return err
+	// the validators convicted by the evidence of the block are punished
+	for _, b := range reorg.Added {
+		for _, ev := range b.Evidence {
+			n.evidence.Punish(ev.PublicKey)
+		}
+	}
+
+	if cert != nil {
+		if err := n.storeCertificate(cert); err != nil {
+			return err
+		}
+	}
+	if len(reorg.Added) == 0 {
+		n.Logger.Info().Msgf("block [%s] height [%d] added to a side branch", hex.EncodeToString(types.HashBlock(block))[:3], block.Header.Height)
+		return nil
+	}
+
+	// the transactions of an abandoned branch go back to the mempool
+	included := make(map[string]bool)
+	for _, b := range reorg.Added {
+		for _, tx := range b.Transactions {
+			included[hex.EncodeToString(types.HashTransaction(tx))] = true
+		}
+	}
+	for _, b := range reorg.Removed {
+		for _, tx := range b.Transactions {
+			if !included[hex.EncodeToString(types.HashTransaction(tx))] {
+				n.mempool.Add(tx)
 			}
 		}
-		// Comment: Add more cases for other message types - block broadcast, etc.
 	}
+	if err := n.storage.CommitReorg(reorg, n.chain.ValidatorSet()); err != nil {
+		return err
+	}
+
+	n.Logger.Info().Msgf("(10) block height [%d] blockStore(M) size [%d] blockStore(P) size [%d] txStore size [%d] headers [%d]",
+		n.chain.Height(), n.chain.blockStore.Size(), n.storage.Size(), n.chain.txStore.Size(), n.chain.Height())
+
 	return nil
 }
 
+// checkProposal runs the chain rules against a block proposed to the consensus
+// engine, a block whose parent is not applied yet is reported as such.
+func (n *Node) checkProposal(block *proto.Block) error {
+	n.applyLock.Lock()
+	defer n.applyLock.Unlock()
+
+	err := n.chain.ValidateBlock(block)
+	if errors.Is(err, ErrOrphanBlock) {
+		return fmt.Errorf("%w: %w", consensus.ErrUnknownParent, err)
+	}
+	return err
+}
+
+// restoreFinality finalizes the blocks that were final before a restart, the
+// chain does not leave them.
+func (n *Node) restoreFinality() {
+	height, err := n.storage.Finalized()
+	if err != nil {
+		n.Logger.Error().Msgf("failed to load the final height: [%s]", err)
+		return
+	}
+	n.chain.Finalize(height)
+}
+
+// storeCertificate persists the commit certificate of a block of the chain.
+func (n *Node) storeCertificate(cert *proto.CommitCertificate) error {
+	n.certs.Put(cert)
+	return n.storage.PutCertificate(cert)
+}
+
+// Broadcast queues a message for all connected peers, it never waits for them.
+func (n *Node) broadcast(msg any) {
+	n.peerLock.RLock()
+	defer n.peerLock.RUnlock()
+
+	for peer, q := range n.queues {
+		if !q.push(msg) {
+			n.Logger.Warn().Msgf("dropping [%T] for peer [%s], its queue is full", msg, n.peers[peer].GetListenAddr())
+		}
+	}
+}
+
+// peerFailed handles a message a peer did not take, a peer that does not
+// take a transaction is removed.
+func (n *Node) peerFailed(peer proto.NodeClient, msg any, err error) {
+	switch v := msg.(type) {
+	case *proto.Transaction:
+		logger.Warn().Msgf("removing peer [%s] from list: [%s]", peer, err)
+		n.deletePeer(peer)
+	case *proto.Block, *proto.BlockSearchResult:
+		logger.Warn().Msgf("failed to deliver block to peer: [%s]", err)
+	case *proto.RaftMessage:
+		logger.Warn().Msgf("failed to deliver [%s] raft message to peer: [%s]", v.Type, err)
+	case *proto.Evidence:
+		logger.Warn().Msgf("failed to deliver evidence to peer: [%s]", err)
+	case *proto.ConsensusMessage:
+		logger.Warn().Msgf("failed to deliver [%s] consensus message to peer: [%s]", v.Type, err)
+	}
+}
+
 func (n *Node) addPeer(c proto.NodeClient, v *proto.Version) {
 	n.peerLock.Lock()
 	defer n.peerLock.Unlock()
@@ -503,6 +1011,7 @@ func (n *Node) addPeer(c proto.NodeClient, v *proto.Version) {
 	}
 
 	n.peers[c] = v
+	n.queues[c] = newPeerQueue(c, func(msg any, err error) { n.peerFailed(c, msg, err) })
 
 	// Connect to all the peers in the peer list
 	if len(v.PeerList) > 0 {
@@ -515,11 +1024,16 @@ func (n *Node) addPeer(c proto.NodeClient, v *proto.Version) {
 func (n *Node) deletePeer(c proto.NodeClient) {
 	n.peerLock.Lock()
 	defer n.peerLock.Unlock()
+	if q, ok := n.queues[c]; ok {
+		q.close()
+	}
 	delete(n.peers, c)
+	delete(n.queues, c)
 }
 
 func (n *Node) bootstrapNetwork(addrs []string) error {
 	//n.Logger.Trace().Msgf("[%s] bootstrap nodes: [%v]", n.listenAddr, addrs)
+	dialed := false
 	for i, addr := range addrs {
 		if !n.canConnectWith(addr) {
 			continue
@@ -551,9 +1065,12 @@ func (n *Node) bootstrapNetwork(addrs []string) error {
 
 		n.addPeer(c, v)
 		n.dialedAddrs[addr] = n.ListenAddr // Mark the address as dialed by this node
+		dialed = true
+	}
 
-		// pull the blockchain from the peer
-
+	// pull the blockchain from the peers
+	if dialed {
+		n.syncPeers()
 	}
 
 	return nil
@@ -574,12 +1091,16 @@ func (n *Node) dialRemoteNode(addr string) (proto.NodeClient, *proto.Version, er
 }
 
 func (n *Node) getVersion() *proto.Version {
-	return &proto.Version{
+	v := &proto.Version{
 		Version:    "darkblock-0.1",
-		Height:     0,
+		Height:     int32(n.storage.Height()),
 		ListenAddr: n.ListenAddr,
 		PeerList:   n.getPeerList(),
 	}
+	if n.PrivateKey != nil {
+		v.Validator = n.PrivateKey.Public().String()
+	}
+	return v
 }
 
 func (n *Node) canConnectWith(addr string) bool {
@@ -0,0 +1,72 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+)
+
+// testFollower returns a node without a validator key that follows the
+// validator set of the given keys, as a PBFT follower does.
+func testFollower(t *testing.T, validators []*crypto.PrivateKey, sinks ...BlockSink) *Node {
+	storage := newStorage(openTestDB(t, t.TempDir()), sinks...)
+	t.Cleanup(func() { storage.Close() })
+
+	keys := make([]*crypto.PublicKey, len(validators))
+	for i, key := range validators {
+		keys[i] = key.Public()
+	}
+	n := &Node{
+		Logger:   &util.Logger,
+		peers:    make(map[proto.NodeClient]*proto.Version),
+		queues:   make(map[proto.NodeClient]*peerQueue),
+		mempool:  NewMempool(),
+		evidence: NewEvidencePool(),
+		chain:    NewChain(storage.blocks, storage.txs),
+		certs:    NewMemoryCertificateStore(),
+		storage:  storage,
+		quit:     make(chan struct{}),
+	}
+	n.chain.SetValidatorSet(types.NewValidatorSet(keys))
+	return n
+}
+
+func TestHandleCertifiedBlock(t *testing.T) {
+	var (
+		keys     = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		follower = testFollower(t, keys)
+		b1       = childBlock(follower.chain.Tip())
+		ctx      = context.Background()
+	)
+	types.SignBlock(keys[0], b1)
+
+	_, err := follower.HandleCertifiedBlock(ctx, &proto.BlockSearchResult{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// a certificate below the quorum is dropped, the block is not final
+	_, err = follower.HandleCertifiedBlock(ctx, &proto.BlockSearchResult{Block: b1, Certificate: certify(b1, keys[:2]...)})
+	require.Nil(t, err)
+	assert.Equal(t, 1, follower.chain.Height())
+	finalized, err := follower.storage.Finalized()
+	require.Nil(t, err)
+	assert.Zero(t, finalized)
+	assert.Nil(t, follower.getCommitCertificate(types.HashBlock(b1)))
+
+	// the certificate of the validators makes the known block final
+	cert := certify(b1, keys[:3]...)
+	_, err = follower.HandleCertifiedBlock(ctx, &proto.BlockSearchResult{Block: b1, Certificate: cert})
+	require.Nil(t, err)
+	finalized, err = follower.storage.Finalized()
+	require.Nil(t, err)
+	assert.Equal(t, 1, finalized)
+	assert.Equal(t, cert.BlockHash, follower.getCommitCertificate(types.HashBlock(b1)).GetBlockHash())
+}
@@ -0,0 +1,241 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/services"
+	"github.com/janrockdev/darkblock/types"
+)
+
+// outboxBatch is how many records a sink reads from the outbox at once.
+const outboxBatch = 64
+
+var (
+	outboxNamespace  = []byte("outbox")
+	outboxSeqKey     = []byte("outboxSeq")
+	sinkCursorPrefix = "sinkCursor_"
+)
+
+// The delivery retries of a failing sink back off from sinkRetryMin up to
+// sinkRetryMax, a sink that is up to date looks for records every outboxPoll.
+var (
+	sinkRetryMin = time.Second
+	sinkRetryMax = time.Minute
+	outboxPoll   = 5 * time.Second
+)
+
+// errScanDone ends a scan of the outbox early.
+var errScanDone = errors.New("scan done")
+
+// Outbox keeps the final blocks of the chain in Badger until every sink has
+// received them. Records are appended in the transaction that commits the
+// change, a sink keeps its own cursor and catches up after an outage.
+type Outbox struct {
+	db     services.DB
+	sinks  []BlockSink
+	notify []chan struct{} // one per sink
+}
+
+func newOutbox(db services.DB, sinks []BlockSink) *Outbox {
+	o := &Outbox{db: db, sinks: sinks}
+	for range sinks {
+		o.notify = append(o.notify, make(chan struct{}, 1))
+	}
+	return o
+}
+
+// outboxRecord is a final block for the sinks.
+type outboxRecord struct {
+	seq   uint64
+	block *proto.Block
+}
+
+// append records final blocks for the sinks, in height order. Without sinks
+// nothing is kept.
+func (o *Outbox) append(txn services.Txn, blocks []*proto.Block) error {
+	if len(o.sinks) == 0 {
+		return nil
+	}
+
+	seq := uint64(0)
+	if ok, err := txn.Has(metaNamespace, outboxSeqKey); err != nil {
+		return err
+	} else if ok {
+		seqBytes, err := txn.Get(metaNamespace, outboxSeqKey)
+		if err != nil {
+			return err
+		}
+		seq, _ = strconv.ParseUint(string(seqBytes), 10, 64)
+	}
+
+	for _, b := range blocks {
+		seq++
+		if err := txn.Put(outboxNamespace, outboxKey(seq), types.BlockBytes(b)); err != nil {
+			return err
+		}
+	}
+	return txn.Put(metaNamespace, outboxSeqKey, []byte(strconv.FormatUint(seq, 10)))
+}
+
+// wake tells the sinks there are new records, once they are committed.
+func (o *Outbox) wake() {
+	for _, notify := range o.notify {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run delivers the outbox to every sink until quit is closed.
+func (o *Outbox) Run(quit <-chan struct{}) {
+	var wg sync.WaitGroup
+	for i, sink := range o.sinks {
+		wg.Add(1)
+		go func(sink BlockSink, notify <-chan struct{}) {
+			defer wg.Done()
+			o.deliver(sink, notify, quit)
+		}(sink, o.notify[i])
+	}
+	wg.Wait()
+}
+
+// deliver sends the records after the cursor of a sink, a failing record is
+// retried with backoff until it goes through.
+func (o *Outbox) deliver(sink BlockSink, notify <-chan struct{}, quit <-chan struct{}) {
+	var (
+		cursor  = o.cursor(sink.Name())
+		backoff = sinkRetryMin
+	)
+	for {
+		records, err := o.read(cursor, outboxBatch)
+		if err != nil {
+			logger.Error().Msgf("failed to read the outbox for sink [%s]: %v", sink.Name(), err)
+		}
+		failed := false
+		for _, r := range records {
+			if err := sink.StoreBlock(r.block); err != nil {
+				logger.Warn().Msgf("sink [%s] failed on block [%d], retrying in [%s]: %v", sink.Name(), r.block.Header.Height, backoff, err)
+				failed = true
+				break
+			}
+			cursor, backoff = r.seq, sinkRetryMin
+			if err := o.setCursor(sink.Name(), cursor); err != nil {
+				logger.Error().Msgf("failed to save the cursor of sink [%s]: %v", sink.Name(), err)
+			}
+		}
+
+		wait, wake := outboxPoll, notify
+		switch {
+		case failed:
+			// new records do not cut a backoff short
+			wait, wake = backoff, nil
+			backoff = min(2*backoff, sinkRetryMax)
+		case len(records) == outboxBatch:
+			continue
+		default:
+			if err := o.prune(); err != nil {
+				logger.Error().Msgf("failed to prune the outbox: %v", err)
+			}
+		}
+
+		select {
+		case <-quit:
+			return
+		case <-wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// read returns up to limit records after the cursor.
+func (o *Outbox) read(cursor uint64, limit int) ([]outboxRecord, error) {
+	var records []outboxRecord
+	err := o.db.Scan(outboxNamespace, nil, outboxKey(cursor+1), limit, func(key, value []byte) error {
+		seq, err := strconv.ParseUint(string(key), 10, 64)
+		if err != nil {
+			return fmt.Errorf("malformed outbox key [%s]: %w", key, err)
+		}
+		b, err := types.UnmarshalBlock(value)
+		if err != nil {
+			return err
+		}
+		records = append(records, outboxRecord{seq: seq, block: b})
+		return nil
+	})
+	return records, err
+}
+
+// prune removes the records every sink has received.
+func (o *Outbox) prune() error {
+	if len(o.sinks) == 0 {
+		return nil
+	}
+	done := o.cursor(o.sinks[0].Name())
+	for _, sink := range o.sinks[1:] {
+		done = min(done, o.cursor(sink.Name()))
+	}
+
+	var keys [][]byte
+	err := o.db.Scan(outboxNamespace, nil, nil, 0, func(key, _ []byte) error {
+		if seq, err := strconv.ParseUint(string(key), 10, 64); err != nil || seq > done {
+			return errScanDone
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errScanDone) {
+		return err
+	}
+	for _, key := range keys {
+		if err := o.db.Delete(outboxNamespace, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Len returns the number of records kept in the outbox.
+func (o *Outbox) Len() int {
+	n, err := o.db.Len(badgerNamespace(outboxNamespace))
+	if err != nil {
+		logger.Error().Msgf("badger access error (Len): [%s]", err)
+	}
+	return int(n)
+}
+
+// cursor returns the last record a sink received, 0 for a new sink.
+func (o *Outbox) cursor(name string) uint64 {
+	key := []byte(sinkCursorPrefix + name)
+	if ok, err := o.db.Has(metaNamespace, key); err != nil || !ok {
+		return 0
+	}
+	cursorBytes, err := o.db.Get(metaNamespace, key)
+	if err != nil {
+		return 0
+	}
+	cursor, _ := strconv.ParseUint(string(cursorBytes), 10, 64)
+	return cursor
+}
+
+func (o *Outbox) setCursor(name string, cursor uint64) error {
+	return o.db.Put(metaNamespace, []byte(sinkCursorPrefix+name), []byte(strconv.FormatUint(cursor, 10)))
+}
+
+// close closes the sinks.
+func (o *Outbox) close() {
+	for _, sink := range o.sinks {
+		if err := sink.Close(); err != nil {
+			logger.Error().Msgf("failed to close sink [%s]: %v", sink.Name(), err)
+		}
+	}
+}
+
+func outboxKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%020d", seq))
+}
@@ -0,0 +1,243 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSink records what it receives, it fails while down or for the first
+// failures calls.
+type testSink struct {
+	lock     sync.Mutex
+	name     string
+	down     bool
+	failures int
+	events   []string // hashes of the stored blocks
+}
+
+func (s *testSink) Name() string { return s.name }
+
+func (s *testSink) StoreBlock(b *proto.Block) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.down || s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, string(types.HashBlock(b)))
+	return nil
+}
+
+func (s *testSink) Close() error { return nil }
+
+func (s *testSink) received() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.events...)
+}
+
+func (s *testSink) setDown(down bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.down = down
+}
+
+// setForTest sets a package variable until the test ends, the tests shorten
+// retries and timeouts with it.
+func setForTest[T any](t *testing.T, v *T, value T) {
+	old := *v
+	*v = value
+	t.Cleanup(func() { *v = old })
+}
+
+func TestOutboxDelivery(t *testing.T) {
+	setForTest(t, &sinkRetryMin, 5*time.Millisecond)
+	setForTest(t, &outboxPoll, 10*time.Millisecond)
+	var (
+		sink    = &testSink{name: "test", failures: 2}
+		storage = newStorage(openTestDB(t, t.TempDir()), sink)
+		genesis = createGenesisBlock()
+		a1      = childBlock(genesis)
+		a2      = childBlock(a1)
+		b2      = childBlock(a1)
+		b3      = childBlock(b2)
+		quit    = make(chan struct{})
+		done    = make(chan struct{})
+	)
+	defer storage.Close()
+
+	// only final blocks go to the sinks, a block finalized again after a
+	// restart is not sent twice
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{a1, a2}, Finalized: []*proto.Block{a1}}, nil))
+	require.Nil(t, storage.CommitReorg(&Reorg{Removed: []*proto.Block{a2}, Added: []*proto.Block{b2, b3}, Finalized: []*proto.Block{b2}}, nil))
+	require.Nil(t, storage.CommitReorg(&Reorg{Finalized: []*proto.Block{a1, b2, b3}}, nil))
+	assert.Equal(t, 3, storage.outbox.Len())
+	finalized, err := storage.Finalized()
+	require.Nil(t, err)
+	assert.Equal(t, 3, finalized)
+
+	go func() {
+		storage.outbox.Run(quit)
+		close(done)
+	}()
+
+	// the failures are retried and the blocks arrive in height order
+	want := []string{
+		string(types.HashBlock(a1)),
+		string(types.HashBlock(b2)),
+		string(types.HashBlock(b3)),
+	}
+	require.Eventually(t, func() bool { return len(sink.received()) == len(want) }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, want, sink.received())
+
+	// delivered records are pruned
+	require.Eventually(t, func() bool { return storage.outbox.Len() == 0 }, time.Second, 5*time.Millisecond)
+	close(quit)
+	<-done
+	assert.Equal(t, uint64(3), storage.outbox.cursor(sink.name))
+}
+
+func TestOutboxCatchUp(t *testing.T) {
+	setForTest(t, &sinkRetryMin, 5*time.Millisecond)
+	setForTest(t, &outboxPoll, 10*time.Millisecond)
+	var (
+		dir     = t.TempDir()
+		sink    = &testSink{name: "test", down: true}
+		storage = newStorage(openTestDB(t, dir), sink)
+		b1      = childBlock(createGenesisBlock())
+		b2      = childBlock(b1)
+		quit    = make(chan struct{})
+		done    = make(chan struct{})
+	)
+
+	// the chain grows while the sink is down
+	go func() {
+		storage.outbox.Run(quit)
+		close(done)
+	}()
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b1}, Finalized: []*proto.Block{b1}}, nil))
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b2}, Finalized: []*proto.Block{b2}}, nil))
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, sink.received())
+	close(quit)
+	<-done
+	require.Nil(t, storage.Close())
+
+	// after a restart the sink receives what it missed
+	sink.setDown(false)
+	storage = newStorage(openTestDB(t, dir), sink)
+	defer storage.Close()
+	quit, done = make(chan struct{}), make(chan struct{})
+	go func() {
+		storage.outbox.Run(quit)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(sink.received()) == 2 }, time.Second, 5*time.Millisecond)
+	close(quit)
+	<-done
+}
+
+func TestOutboxReopensSink(t *testing.T) {
+	setForTest(t, &sinkRetryMin, 5*time.Millisecond)
+	setForTest(t, &outboxPoll, 10*time.Millisecond)
+	var (
+		up    = &testSink{name: "up"}
+		late  = &testSink{name: "late"}
+		lock  sync.Mutex
+		opens = 0
+		reach = false
+		b1    = childBlock(createGenesisBlock())
+		quit  = make(chan struct{})
+		done  = make(chan struct{})
+	)
+	reopening := openSink(late.name, func() (BlockSink, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		opens++
+		if !reach {
+			return nil, errors.New("sink unreachable")
+		}
+		return late, nil
+	})
+	storage := newStorage(openTestDB(t, t.TempDir()), up, reopening)
+	defer storage.Close()
+
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b1}, Finalized: []*proto.Block{b1}}, nil))
+	go func() {
+		storage.outbox.Run(quit)
+		close(done)
+	}()
+
+	// the record stays in the outbox until the sink opened and received it
+	require.Eventually(t, func() bool { return len(up.received()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return opens > 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, storage.outbox.Len())
+
+	lock.Lock()
+	reach = true
+	lock.Unlock()
+	require.Eventually(t, func() bool { return len(late.received()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return storage.outbox.Len() == 0 }, time.Second, 5*time.Millisecond)
+	close(quit)
+	<-done
+}
+
+func TestOutboxFollowerDelivery(t *testing.T) {
+	setForTest(t, &sinkRetryMin, 5*time.Millisecond)
+	setForTest(t, &outboxPoll, 10*time.Millisecond)
+	var (
+		keys     = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		sink     = &testSink{name: "test"}
+		follower = testFollower(t, keys, sink)
+		b1       = childBlock(follower.chain.Tip())
+		b2       = childBlock(b1)
+		quit     = make(chan struct{})
+		done     = make(chan struct{})
+	)
+	types.SignBlock(keys[0], b1)
+	b2.Header.PrevHash = types.HashBlock(b1)
+	types.SignBlock(keys[1], b2)
+	go func() {
+		follower.storage.outbox.Run(quit)
+		close(done)
+	}()
+	defer func() {
+		close(quit)
+		<-done
+	}()
+
+	// a block pushed without a certificate is not final on a follower
+	_, err := follower.HandleBlock(context.Background(), b1)
+	require.Nil(t, err)
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, sink.received())
+
+	// the certificate of the next block finalizes it and its parent, the sink
+	// does not wait for the block tree to be pruned
+	_, err = follower.HandleCertifiedBlock(context.Background(), &proto.BlockSearchResult{Block: b2, Certificate: certify(b2, keys[:3]...)})
+	require.Nil(t, err)
+	require.Eventually(t, func() bool { return len(sink.received()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{string(types.HashBlock(b1)), string(types.HashBlock(b2))}, sink.received())
+}
+
+func TestOutboxWithoutSinks(t *testing.T) {
+	storage := newStorage(openTestDB(t, t.TempDir()))
+	defer storage.Close()
+
+	b1 := childBlock(createGenesisBlock())
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b1}, Finalized: []*proto.Block{b1}}, nil))
+	assert.Zero(t, storage.outbox.Len())
+}
@@ -0,0 +1,99 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/janrockdev/darkblock/proto"
+)
+
+// peerQueueSize is how many messages wait for one peer, more are dropped.
+const peerQueueSize = 256
+
+// peerTimeout bounds every call to a peer, a stalled peer holds up its own
+// queue for at most this long per message.
+var peerTimeout = 5 * time.Second
+
+// peerQueue sends the messages for one peer in order from its own goroutine,
+// a slow or stalled peer never holds up the node or the other peers.
+type peerQueue struct {
+	client proto.NodeClient
+	msgs   chan any
+	quit   chan struct{}
+	once   sync.Once
+
+	// failed is called with the messages the peer did not take
+	failed func(msg any, err error)
+}
+
+func newPeerQueue(client proto.NodeClient, failed func(msg any, err error)) *peerQueue {
+	q := &peerQueue{
+		client: client,
+		msgs:   make(chan any, peerQueueSize),
+		quit:   make(chan struct{}),
+		failed: failed,
+	}
+	go q.run()
+	return q
+}
+
+// push queues a message without waiting, it reports false when the queue is
+// full or closed and the message is dropped.
+func (q *peerQueue) push(msg any) bool {
+	select {
+	case <-q.quit:
+		return false
+	default:
+	}
+	select {
+	case q.msgs <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops the queue, the messages still waiting are dropped.
+func (q *peerQueue) close() {
+	q.once.Do(func() { close(q.quit) })
+}
+
+func (q *peerQueue) run() {
+	for {
+		select {
+		case <-q.quit:
+			return
+		case msg := <-q.msgs:
+			ctx, cancel := context.WithTimeout(context.Background(), peerTimeout)
+			err := sendToPeer(ctx, q.client, msg)
+			cancel()
+			if err != nil && q.failed != nil {
+				q.failed(msg, err)
+			}
+		}
+	}
+}
+
+// sendToPeer delivers a message with the call of its type.
+func sendToPeer(ctx context.Context, peer proto.NodeClient, msg any) error {
+	var err error
+	switch v := msg.(type) {
+	case *proto.Transaction:
+		_, err = peer.HandleTransaction(ctx, v)
+	case *proto.Block:
+		_, err = peer.HandleBlock(ctx, v)
+	case *proto.BlockSearchResult:
+		_, err = peer.HandleCertifiedBlock(ctx, v)
+	case *proto.RaftMessage:
+		_, err = peer.HandleRaftMessage(ctx, v)
+	case *proto.Evidence:
+		_, err = peer.HandleEvidence(ctx, v)
+	case *proto.ConsensusMessage:
+		_, err = peer.HandleConsensusMessage(ctx, v)
+	default:
+		err = fmt.Errorf("unknown message [%T]", msg)
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package node
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/janrockdev/darkblock/proto"
+)
+
+// stalledPeer takes consensus messages only once released, until then every
+// call waits for its deadline.
+type stalledPeer struct {
+	proto.NodeClient
+	release chan struct{}
+
+	mu       sync.Mutex
+	received []string
+}
+
+func (p *stalledPeer) HandleConsensusMessage(ctx context.Context, msg *proto.ConsensusMessage, opts ...grpc.CallOption) (*proto.Ack, error) {
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.received = append(p.received, msg.Type)
+	return &proto.Ack{}, nil
+}
+
+func (p *stalledPeer) messages() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.received...)
+}
+
+func TestPeerQueueStalledPeer(t *testing.T) {
+	setForTest(t, &peerTimeout, 20*time.Millisecond)
+
+	var (
+		peer   = &stalledPeer{release: make(chan struct{})}
+		failed = make(chan string, peerQueueSize+2)
+		q      = newPeerQueue(peer, func(msg any, err error) {
+			failed <- msg.(*proto.ConsensusMessage).Type
+		})
+	)
+	defer q.close()
+
+	// pushing never waits for the peer, a full queue drops the message
+	start := time.Now()
+	pushed := 0
+	for range peerQueueSize + 2 {
+		if q.push(&proto.ConsensusMessage{Type: "Prepare"}) {
+			pushed++
+		}
+	}
+	assert.Less(t, time.Since(start), peerTimeout)
+	assert.Less(t, pushed, peerQueueSize+2)
+	assert.GreaterOrEqual(t, pushed, peerQueueSize)
+
+	// every call gives up at its deadline, the queue moves on
+	select {
+	case msg := <-failed:
+		assert.Equal(t, "Prepare", msg)
+	case <-time.After(time.Second):
+		t.Fatal("the call to the stalled peer did not time out")
+	}
+
+	// once the peer takes messages again they are delivered
+	close(peer.release)
+	assert.Eventually(t, func() bool { return len(q.msgs) == 0 }, 10*time.Second, time.Millisecond)
+	q.push(&proto.ConsensusMessage{Type: "Commit"})
+	assert.Eventually(t, func() bool {
+		msgs := peer.messages()
+		return len(msgs) > 0 && msgs[len(msgs)-1] == "Commit"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// a closed queue takes no more messages
+	q.close()
+	assert.False(t, q.push(&proto.ConsensusMessage{Type: "Commit"}))
+}
@@ -0,0 +1,98 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/janrockdev/darkblock/config"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/services"
+)
+
+// BlockSink is a secondary store fed with the final blocks of the chain
+// through the outbox. Blocks arrive in height order and at least once, a sink
+// must accept a block it has already seen.
+type BlockSink interface {
+	Name() string // unique, the delivery cursor of the sink is kept under it
+	StoreBlock(b *proto.Block) error
+	Close() error
+}
+
+// openSinks opens the sinks enabled in the config. A sink that cannot be
+// opened stays registered and is opened again with the next delivery, the
+// outbox keeps its records until it caught up.
+func openSinks(cfg *config.ConfigFile) []BlockSink {
+	var sinks []BlockSink
+
+	if cb := cfg.SINKS.COUCHBASE; cb.Enabled {
+		sinks = append(sinks, openSink("couchbase", func() (BlockSink, error) {
+			return services.NewCouchbaseService(cb.Connection, cb.Username, cb.Password, cb.BlockBucket, cb.TxBucket)
+		}))
+	}
+
+	if fc := cfg.SINKS.FILE; fc.Enabled {
+		sinks = append(sinks, openSink("file", func() (BlockSink, error) {
+			return services.NewFileSink(fc.Dir, fc.SegmentBlocks, fc.SegmentBytes)
+		}))
+	}
+
+	return sinks
+}
+
+// openSink opens a sink, one that fails is retried by a reopeningSink.
+func openSink(name string, open func() (BlockSink, error)) BlockSink {
+	sink, err := open()
+	if err == nil {
+		return sink
+	}
+	logger.Error().Msgf("failed to open sink [%s], retrying with the deliveries: %v", name, err)
+	return &reopeningSink{name: name, open: open}
+}
+
+// reopeningSink stands in for a sink that could not be opened. Every delivery
+// tries to open it first, a failure is a failed delivery the outbox retries
+// with backoff.
+type reopeningSink struct {
+	name string
+	open func() (BlockSink, error)
+
+	lock sync.Mutex
+	sink BlockSink
+}
+
+func (s *reopeningSink) Name() string {
+	return s.name
+}
+
+func (s *reopeningSink) StoreBlock(b *proto.Block) error {
+	sink, err := s.opened()
+	if err != nil {
+		return err
+	}
+	return sink.StoreBlock(b)
+}
+
+func (s *reopeningSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.sink == nil {
+		return nil
+	}
+	return s.sink.Close()
+}
+
+// opened returns the sink, opening it when it is not open yet.
+func (s *reopeningSink) opened() (BlockSink, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.sink != nil {
+		return s.sink, nil
+	}
+	sink, err := s.open()
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	logger.Info().Msgf("opened sink [%s]", s.name)
+	s.sink = sink
+	return sink, nil
+}
@@ -0,0 +1,377 @@
+package node
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/services"
+	"github.com/janrockdev/darkblock/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+var (
+	certificateNamespace = []byte("commitCert")
+	governanceNamespace  = []byte("governance")
+	validatorSetKey      = []byte("validatorSet")
+	prevValidatorsPrefix = "prev_"              // validator set before a block that changed it, by block hash
+	lastSignedKey        = []byte("lastSigned") // in meta
+	finalizedKey         = []byte("finalized")  // in meta, height of the last final block
+)
+
+// Storage is the persistence of a node: one Badger database for the chain, an
+// outbox feeding the committed blocks to the secondary stores and the webhooks
+// notifying subscribers of their transactions. It is opened
+// with the node and closed when the node stops, all block and tx reads and
+// writes go through it.
+type Storage struct {
+	db       services.DB
+	blocks   *BadgerBlockStore
+	txs      *BadgerTXStore
+	outbox   *Outbox
+	webhooks *Webhooks
+
+	payloadFields []string // top-level fields of JSON payloads indexed for search
+}
+
+// OpenStorage opens the Badger database in dataDir, indexing the given fields
+// of JSON payloads and feeding the committed blocks to the sinks.
+func OpenStorage(dataDir string, payloadFields []string, sinks ...BlockSink) (*Storage, error) {
+	db, err := services.ConnectBadgerDB(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	s := newStorage(db, sinks...)
+	s.payloadFields = payloadFields
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.webhooks.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func newStorage(db services.DB, sinks ...BlockSink) *Storage {
+	return &Storage{
+		db:       db,
+		blocks:   NewBadgerBlockStore(db),
+		txs:      NewBadgerTXStore(db),
+		outbox:   newOutbox(db, sinks),
+		webhooks: newWebhooks(db),
+	}
+}
+
+// Close closes the sinks and the database, the outbox must not be running.
+func (s *Storage) Close() error {
+	s.outbox.close()
+	return s.db.Close()
+}
+
+// Tip returns the height and hash of the last persisted block, a new chain
+// has no tip and returns a nil hash.
+func (s *Storage) Tip() (int, []byte, error) {
+	if ok, err := s.db.Has(metaNamespace, tipKey); err != nil || !ok {
+		return 0, nil, err
+	}
+	tipBytes, err := s.db.Get(metaNamespace, tipKey)
+	if err != nil {
+		return 0, nil, err
+	}
+	return decodeTip(tipBytes)
+}
+
+// Height returns the height of the last persisted block, 0 for a new chain.
+func (s *Storage) Height() int {
+	height, _, err := s.Tip()
+	if err != nil {
+		logger.Error().Msgf("badger access error (Tip): [%s]", err)
+	}
+	return height
+}
+
+// Finalized returns the height of the last final block, 0 when no block is
+// final yet.
+func (s *Storage) Finalized() (int, error) {
+	if ok, err := s.db.Has(metaNamespace, finalizedKey); err != nil || !ok {
+		return 0, err
+	}
+	heightBytes, err := s.db.Get(metaNamespace, finalizedKey)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(heightBytes))
+}
+
+// GetBlockByHeight returns a block of the persisted canonical chain.
+func (s *Storage) GetBlockByHeight(height int) (*proto.Block, error) {
+	hash, err := s.db.Get(heightIndexNamespace, heightKey(int32(height)))
+	if err != nil {
+		return nil, fmt.Errorf("height [%d] not found: %w", height, err)
+	}
+	return s.blocks.Get(string(hash))
+}
+
+// ReadBlocks returns the persisted blocks of a range together with their
+// commit certificates.
+func (s *Storage) ReadBlocks(from, to int) ([]*proto.BlockSearchResult, error) {
+	results := make([]*proto.BlockSearchResult, 0, to-from+1)
+	for height := from; height <= to; height++ {
+		block, err := s.GetBlockByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+		res := &proto.BlockSearchResult{Block: block}
+		if cert, err := s.Certificate(types.HashBlock(block)); err == nil {
+			res.Certificate = cert
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// Size returns the number of blocks of the persisted canonical chain, the
+// genesis block is not persisted.
+func (s *Storage) Size() int {
+	return s.Height()
+}
+
+// Certificate returns the commit certificate of a block.
+func (s *Storage) Certificate(hash []byte) (*proto.CommitCertificate, error) {
+	certBytes, err := s.db.Get(certificateNamespace, []byte(hex.EncodeToString(hash)))
+	if err != nil {
+		return nil, err
+	}
+	cert := &proto.CommitCertificate{}
+	if err := pb.Unmarshal(certBytes, cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// PutCertificate persists the commit certificate of a block.
+func (s *Storage) PutCertificate(cert *proto.CommitCertificate) error {
+	certBytes, err := pb.Marshal(cert)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(certificateNamespace, []byte(hex.EncodeToString(cert.BlockHash)), certBytes)
+}
+
+// ValidatorSet returns the validator set persisted with the last block, nil
+// when none was persisted.
+func (s *Storage) ValidatorSet() (*proto.ValidatorSet, error) {
+	if ok, err := s.db.Has(governanceNamespace, validatorSetKey); err != nil || !ok {
+		return nil, err
+	}
+	setBytes, err := s.db.Get(governanceNamespace, validatorSetKey)
+	if err != nil {
+		return nil, err
+	}
+	set := &proto.ValidatorSet{}
+	if err := pb.Unmarshal(setBytes, set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// LastSigned returns the last block this validator signed, nil when it never
+// signed one.
+func (s *Storage) LastSigned() (*proto.Block, error) {
+	if ok, err := s.db.Has(metaNamespace, lastSignedKey); err != nil || !ok {
+		return nil, err
+	}
+	blockBytes, err := s.db.Get(metaNamespace, lastSignedKey)
+	if err != nil {
+		return nil, err
+	}
+	b := &proto.Block{}
+	if err := pb.Unmarshal(blockBytes, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// PutLastSigned persists the last block this validator signed, it is written
+// before the block leaves the node.
+func (s *Storage) PutLastSigned(b *proto.Block) error {
+	blockBytes, err := pb.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(metaNamespace, lastSignedKey, blockBytes)
+}
+
+// CommitReorg persists a change of the canonical chain and the validator set
+// after it. The blocks, their indexes, the tip, the validator set, the outbox
+// records of the sinks and the webhook notifications are written in one
+// transaction, a crash leaves either the old or the new chain. The sinks and
+// the webhooks receive the blocks once they are final.
+func (s *Storage) CommitReorg(reorg *Reorg, set *proto.ValidatorSet) error {
+	err := s.db.Update(func(txn services.Txn) error {
+		for _, b := range reorg.Removed {
+			if err := s.unindexBlock(txn, b); err != nil {
+				return err
+			}
+		}
+		for _, b := range reorg.Added {
+			if err := s.indexBlock(txn, b); err != nil {
+				return err
+			}
+		}
+		if tip := reorgTip(reorg); tip != nil {
+			if err := txn.Put(metaNamespace, tipKey, tip); err != nil {
+				return err
+			}
+		}
+		if final, err := finalBlocks(txn, reorg); err != nil {
+			return err
+		} else if len(final) > 0 {
+			if err := s.outbox.append(txn, final); err != nil {
+				return err
+			}
+			if err := s.webhooks.enqueue(txn, final); err != nil {
+				return err
+			}
+			height := strconv.Itoa(int(final[len(final)-1].Header.Height))
+			if err := txn.Put(metaNamespace, finalizedKey, []byte(height)); err != nil {
+				return err
+			}
+		}
+		for hash, prev := range reorg.PrevValidators {
+			prevBytes, err := pb.Marshal(prev)
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(governanceNamespace, []byte(prevValidatorsPrefix+hash), prevBytes); err != nil {
+				return err
+			}
+		}
+		if set != nil {
+			setBytes, err := pb.Marshal(set)
+			if err != nil {
+				return err
+			}
+			if err := txn.Put(governanceNamespace, validatorSetKey, setBytes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.outbox.wake()
+	s.webhooks.wake()
+	return nil
+}
+
+// finalBlocks returns the blocks a reorg finalized above the persisted final
+// height, after a restart the chain finalizes some of them again.
+func finalBlocks(txn services.Txn, reorg *Reorg) ([]*proto.Block, error) {
+	finalized := 0
+	if ok, err := txn.Has(metaNamespace, finalizedKey); err != nil {
+		return nil, err
+	} else if ok {
+		heightBytes, err := txn.Get(metaNamespace, finalizedKey)
+		if err != nil {
+			return nil, err
+		}
+		if finalized, err = strconv.Atoi(string(heightBytes)); err != nil {
+			return nil, err
+		}
+	}
+
+	var final []*proto.Block
+	for _, b := range reorg.Finalized {
+		if int(b.Header.Height) > finalized {
+			final = append(final, b)
+		}
+	}
+	return final, nil
+}
+
+// reorgTip returns the encoded tip after a reorg, the last added block or the
+// fork point when blocks were only removed. An empty reorg keeps the tip.
+func reorgTip(reorg *Reorg) []byte {
+	if n := len(reorg.Added); n > 0 {
+		b := reorg.Added[n-1]
+		return encodeTip(b.Header.Height, types.HashBlock(b))
+	}
+	if len(reorg.Removed) > 0 {
+		b := reorg.Removed[0]
+		return encodeTip(b.Header.Height-1, b.Header.PrevHash)
+	}
+	return nil
+}
+
+// encodeTip stores a tip in the format of the legacy chain keys, height_hash.
+func encodeTip(height int32, hash []byte) []byte {
+	return []byte(fmt.Sprintf("%016d_%s", height, hex.EncodeToString(hash)))
+}
+
+func decodeTip(tipBytes []byte) (int, []byte, error) {
+	heightStr, hashStr, ok := strings.Cut(string(tipBytes), "_")
+	if !ok {
+		return 0, nil, fmt.Errorf("malformed tip [%s]", tipBytes)
+	}
+	height, err := strconv.Atoi(heightStr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("malformed tip [%s]: %w", tipBytes, err)
+	}
+	hash, err := hex.DecodeString(hashStr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("malformed tip [%s]: %w", tipBytes, err)
+	}
+	return height, hash, nil
+}
+
+// migrate moves the canonical chain of a database written before the height
+// index existed into the indexes and brings older indexes up to date. Every block is moved in its own transaction
+// and removed from the old namespace, an interrupted migration resumes on the
+// next start.
+func (s *Storage) migrate() error {
+	if ok, err := s.db.Has(metaNamespace, blockCountKey); err != nil {
+		return err
+	} else if !ok {
+		n, err := s.db.Len(badgerNamespace(blockByHashNamespace))
+		if err != nil {
+			return err
+		}
+		if err := s.db.Put(metaNamespace, blockCountKey, []byte(strconv.Itoa(int(n)))); err != nil {
+			return err
+		}
+	}
+
+	migrated := 0
+	err := s.db.Iterate(legacyCanonicalNamespace, func(key, value []byte) error {
+		b, err := types.UnmarshalBlock(value)
+		if err != nil {
+			return err
+		}
+		hash := types.HashBlock(b)
+		migrated++
+		return s.db.Update(func(txn services.Txn) error {
+			if err := s.indexBlock(txn, b); err != nil {
+				return err
+			}
+			if err := txn.Put(metaNamespace, tipKey, encodeTip(b.Header.Height, hash)); err != nil {
+				return err
+			}
+			return txn.Delete(legacyCanonicalNamespace, key)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if migrated > 0 {
+		logger.Info().Msgf("moved [%d] blocks to the height index", migrated)
+	}
+	return s.reindex()
+}
@@ -0,0 +1,124 @@
+package node
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageCommitReorg(t *testing.T) {
+	var (
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		chain   = NewChain(storage.blocks, storage.txs)
+		genesis = chain.tip.block
+		a1      = childBlock(genesis)
+		a2      = childBlock(a1)
+		b1      = childBlock(genesis)
+		b2      = childBlock(b1)
+		b3      = childBlock(b2)
+		set     = types.NewValidatorSet([]*crypto.PublicKey{crypto.GeneratePrivateKey().Public()})
+	)
+	defer storage.Close()
+
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{a1, a2}}, nil))
+	assert.Equal(t, 2, storage.Height())
+	got, err := storage.ValidatorSet()
+	require.Nil(t, err)
+	assert.Nil(t, got)
+
+	// the abandoned branch is removed by height before the new one is stored
+	require.Nil(t, storage.CommitReorg(&Reorg{Removed: []*proto.Block{a1, a2}, Added: []*proto.Block{b1, b2, b3}}, set))
+	assert.Equal(t, 3, storage.Height())
+	assert.Equal(t, 3, storage.Size())
+	results, err := storage.ReadBlocks(1, 3)
+	require.Nil(t, err)
+	for i, b := range []*proto.Block{b1, b2, b3} {
+		assert.Equal(t, types.HashBlock(b), types.HashBlock(results[i].Block))
+		assert.Nil(t, results[i].Certificate)
+	}
+	got, err = storage.ValidatorSet()
+	require.Nil(t, err)
+	assert.Equal(t, types.ValidatorSetKeys(set), types.ValidatorSetKeys(got))
+
+	// dropping blocks moves the tip back to the fork point
+	require.Nil(t, storage.CommitReorg(&Reorg{Removed: []*proto.Block{b3}}, nil))
+	height, hash, err := storage.Tip()
+	require.Nil(t, err)
+	assert.Equal(t, 2, height)
+	assert.Equal(t, types.HashBlock(b2), hash)
+	_, err = storage.GetBlockByHeight(3)
+	assert.Error(t, err)
+	// every block stays available by hash, genesis included
+	assert.Equal(t, 6, storage.blocks.Size())
+}
+
+func TestStorageMigrate(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		db     = openTestDB(t, dir)
+		blocks = []*proto.Block{childBlock(createGenesisBlock())}
+	)
+	for i := 0; i < 4; i++ {
+		blocks = append(blocks, childBlock(blocks[i]))
+	}
+	// older databases kept the canonical chain by height only
+	for _, b := range blocks {
+		require.Nil(t, db.Set(legacyCanonicalNamespace, []byte(hex.EncodeToString(types.HashBlock(b))), int64(b.Header.Height), types.BlockBytes(b)))
+	}
+	require.Nil(t, db.Close())
+
+	storage, err := OpenStorage(dir, nil)
+	require.Nil(t, err)
+	defer storage.Close()
+	assert.Equal(t, 5, storage.Height())
+	assert.Equal(t, 5, storage.blocks.Size())
+	for _, b := range blocks {
+		got, err := storage.GetBlockByHeight(int(b.Header.Height))
+		require.Nil(t, err)
+		assert.Equal(t, types.HashBlock(b), types.HashBlock(got))
+	}
+	n, err := storage.db.Len(badgerNamespace(legacyCanonicalNamespace))
+	require.Nil(t, err)
+	assert.Zero(t, n)
+}
+
+func TestStorageCertificate(t *testing.T) {
+	var (
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		cert    = &proto.CommitCertificate{BlockHash: types.HashBlock(childBlock(createGenesisBlock())), View: 3}
+	)
+	defer storage.Close()
+
+	_, err := storage.Certificate(cert.BlockHash)
+	assert.Error(t, err)
+	require.Nil(t, storage.PutCertificate(cert))
+	got, err := storage.Certificate(cert.BlockHash)
+	require.Nil(t, err)
+	assert.Equal(t, cert.View, got.View)
+}
+
+func TestStorageLastSigned(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		storage = newStorage(openTestDB(t, dir))
+		block   = childBlock(createGenesisBlock())
+	)
+
+	got, err := storage.LastSigned()
+	require.Nil(t, err)
+	assert.Nil(t, got)
+	require.Nil(t, storage.PutLastSigned(block))
+	require.Nil(t, storage.Close())
+
+	// the signed block is kept across restarts
+	storage = newStorage(openTestDB(t, dir))
+	defer storage.Close()
+	got, err = storage.LastSigned()
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(block), types.HashBlock(got))
+}
@@ -3,10 +3,27 @@ package node
 import (
 	"encoding/hex"
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/services"
 	"github.com/janrockdev/darkblock/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Badger namespaces of the persistent stores. Every known block is kept by
+// hash in blockByHash, the canonical chain is indexed by height in blockHeight
+// and its tip is kept in meta. Older databases kept the canonical blocks by
+// height in blockStore, they are moved to the indexes when opened.
+var (
+	legacyCanonicalNamespace = []byte("blockStore")
+	blockByHashNamespace     = []byte("blockByHash")
+	heightIndexNamespace     = []byte("blockHeight")
+	txByHashNamespace        = []byte("txByHash")
+	metaNamespace            = []byte("meta")
+	tipKey                   = []byte("tip")
+	blockCountKey            = []byte("blockCount")
 )
 
 // UTXO storer interface.
@@ -128,6 +145,88 @@ func (s *MemoryTXStore) Clear() error {
 	return nil
 }
 
+// Badger TX struct.
+type BadgerTXStore struct {
+	db services.DB
+}
+
+// NewBadgerTXStore creates a TX store on an open Badger database.
+func NewBadgerTXStore(db services.DB) *BadgerTXStore {
+	return &BadgerTXStore{db: db}
+}
+
+// Get retrieves a TX from the store.
+func (s *BadgerTXStore) Get(hash string) (*proto.Transaction, error) {
+	txBytes, err := s.db.Get(txByHashNamespace, []byte(hash))
+	if err != nil {
+		return nil, fmt.Errorf("tx [%s] not found", hash)
+	}
+	tx := &proto.Transaction{}
+	if err := pb.Unmarshal(txBytes, tx); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Put stores a TX in the store.
+func (s *BadgerTXStore) Put(tx *proto.Transaction) error {
+	txBytes, err := pb.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	hash := hex.EncodeToString(types.HashTransaction(tx))
+
+	return s.db.Put(txByHashNamespace, []byte(hash), txBytes)
+}
+
+// Size returns the number of transactions in the store.
+func (s *BadgerTXStore) Size() int {
+	n, err := s.db.Len(badgerNamespace(txByHashNamespace))
+	if err != nil {
+		logger.Error().Msgf("badger access error (Len): [%s]", err)
+	}
+
+	return int(n)
+}
+
+// GetAll returns all transactions in the store.
+func (s *BadgerTXStore) GetAll() []*proto.Transaction {
+	txs := []*proto.Transaction{}
+	err := s.db.Iterate(txByHashNamespace, func(_, value []byte) error {
+		tx := &proto.Transaction{}
+		if err := pb.Unmarshal(value, tx); err != nil {
+			return err
+		}
+		txs = append(txs, tx)
+		return nil
+	})
+	if err != nil {
+		logger.Error().Msgf("badger access error (Iterate): [%s]", err)
+	}
+
+	return txs
+}
+
+// Clear removes all transactions from the store.
+func (s *BadgerTXStore) Clear() error {
+	keys := [][]byte{}
+	err := s.db.Iterate(txByHashNamespace, func(key, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.db.Delete(txByHashNamespace, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Block storer interface.
 type BlockStorer interface {
 	Put(*proto.Block) error
@@ -176,3 +275,148 @@ func (s *MemoryBlockStore) Size() int {
 
 	return len(s.blocks)
 }
+
+// Badger block struct.
+type BadgerBlockStore struct {
+	db services.DB
+}
+
+// NewBadgerBlockStore creates a block store on an open Badger database.
+func NewBadgerBlockStore(db services.DB) *BadgerBlockStore {
+	return &BadgerBlockStore{db: db}
+}
+
+// Put stores a block in the store.
+func (s *BadgerBlockStore) Put(b *proto.Block) error {
+	return s.db.Update(func(txn services.Txn) error {
+		return putBlock(txn, b)
+	})
+}
+
+// Get retrieves a block from the store.
+func (s *BadgerBlockStore) Get(hash string) (*proto.Block, error) {
+	blockBytes, err := s.db.Get(blockByHashNamespace, []byte(hash))
+	if err != nil {
+		return nil, fmt.Errorf("block [%s] not found", hash)
+	}
+
+	return types.UnmarshalBlock(blockBytes)
+}
+
+// Size returns the number of blocks in the store.
+func (s *BadgerBlockStore) Size() int {
+	if ok, err := s.db.Has(metaNamespace, blockCountKey); err != nil || !ok {
+		return 0
+	}
+	countBytes, err := s.db.Get(metaNamespace, blockCountKey)
+	if err != nil {
+		logger.Error().Msgf("badger access error (Get): [%s]", err)
+		return 0
+	}
+	n, _ := strconv.Atoi(string(countBytes))
+
+	return n
+}
+
+// Canonical calls fn for the blocks of the canonical chain in height order,
+// the genesis block is not persisted.
+func (s *BadgerBlockStore) Canonical(fn func(*proto.Block) error) error {
+	return s.db.Iterate(heightIndexNamespace, func(_, hash []byte) error {
+		b, err := s.Get(string(hash))
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+// PrevValidators returns the validator set before a canonical block that
+// changed it, nil when the block left the set as it was.
+func (s *BadgerBlockStore) PrevValidators(hash []byte) (*proto.ValidatorSet, error) {
+	key := []byte(prevValidatorsPrefix + hex.EncodeToString(hash))
+	if ok, err := s.db.Has(governanceNamespace, key); err != nil || !ok {
+		return nil, err
+	}
+	setBytes, err := s.db.Get(governanceNamespace, key)
+	if err != nil {
+		return nil, err
+	}
+	set := &proto.ValidatorSet{}
+	if err := pb.Unmarshal(setBytes, set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// putBlock stores a block by hash and counts it, a known block is left as is.
+func putBlock(txn services.Txn, b *proto.Block) error {
+	hash := []byte(hex.EncodeToString(types.HashBlock(b)))
+	if ok, err := txn.Has(blockByHashNamespace, hash); err != nil || ok {
+		return err
+	}
+	if err := txn.Put(blockByHashNamespace, hash, types.BlockBytes(b)); err != nil {
+		return err
+	}
+
+	count := 0
+	if ok, err := txn.Has(metaNamespace, blockCountKey); err != nil {
+		return err
+	} else if ok {
+		countBytes, err := txn.Get(metaNamespace, blockCountKey)
+		if err != nil {
+			return err
+		}
+		count, _ = strconv.Atoi(string(countBytes))
+	}
+	return txn.Put(metaNamespace, blockCountKey, []byte(strconv.Itoa(count+1)))
+}
+
+// heightKey is the key of a height in the height index, zero padded so the
+// index iterates in height order.
+func heightKey(height int32) []byte {
+	return []byte(fmt.Sprintf("%016d", height))
+}
+
+// badgerNamespace is the key prefix of a namespace, for the calls that take a
+// raw prefix.
+func badgerNamespace(namespace []byte) []byte {
+	return append(append([]byte{}, namespace...), '/')
+}
+
+// Commit certificate storer interface.
+type CertificateStorer interface {
+	Put(*proto.CommitCertificate) error
+	Get(string) (*proto.CommitCertificate, error)
+}
+
+// Commit certificate struct.
+type MemoryCertificateStore struct {
+	lock  sync.RWMutex
+	certs map[string]*proto.CommitCertificate
+}
+
+// NewMemoryCertificateStore creates a new in-memory commit certificate store.
+func NewMemoryCertificateStore() *MemoryCertificateStore {
+	return &MemoryCertificateStore{certs: make(map[string]*proto.CommitCertificate)}
+}
+
+// Put stores a commit certificate under the hash of the certified block.
+func (s *MemoryCertificateStore) Put(cert *proto.CommitCertificate) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.certs[hex.EncodeToString(cert.BlockHash)] = cert
+
+	return nil
+}
+
+// Get retrieves the commit certificate of a block.
+func (s *MemoryCertificateStore) Get(hash string) (*proto.CommitCertificate, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	cert, ok := s.certs[hash]
+	if !ok {
+		return nil, fmt.Errorf("commit certificate of block [%s] not found", hash)
+	}
+
+	return cert, nil
+}
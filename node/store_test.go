@@ -0,0 +1,144 @@
+package node
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/services"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T, dir string) services.DB {
+	db, err := services.ConnectBadgerDB(dir)
+	require.Nil(t, err)
+	return db
+}
+
+func TestBadgerTXStore(t *testing.T) {
+	var (
+		db    = openTestDB(t, t.TempDir())
+		store = NewBadgerTXStore(db)
+		tx    = signedTx(crypto.GeneratePrivateKey())
+		hash  = hex.EncodeToString(types.HashTransaction(tx))
+	)
+	defer db.Close()
+
+	_, err := store.Get(hash)
+	assert.Error(t, err)
+	require.Nil(t, store.Put(tx))
+	got, err := store.Get(hash)
+	require.Nil(t, err)
+	assert.Equal(t, hash, hex.EncodeToString(types.HashTransaction(got)))
+	assert.Equal(t, 1, store.Size())
+	assert.Len(t, store.GetAll(), 1)
+
+	require.Nil(t, store.Clear())
+	assert.Equal(t, 0, store.Size())
+}
+
+func TestBadgerBlockStore(t *testing.T) {
+	var (
+		db    = openTestDB(t, t.TempDir())
+		store = NewBadgerBlockStore(db)
+		chain = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+		b     = randomBlock(t, chain)
+		hash  = hex.EncodeToString(types.HashBlock(b))
+	)
+	defer db.Close()
+
+	_, err := store.Get(hash)
+	assert.Error(t, err)
+	require.Nil(t, store.Put(b))
+	got, err := store.Get(hash)
+	require.Nil(t, err)
+	assert.Equal(t, types.HashBlock(b), types.HashBlock(got))
+	assert.Equal(t, 1, store.Size())
+}
+
+func TestNewChainLoadsBlockStore(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		storage = newStorage(openTestDB(t, dir))
+		chain   = NewChain(storage.blocks, storage.txs)
+		blocks  = []*proto.Block{}
+	)
+	for i := 0; i < 5; i++ {
+		b := randomBlock(t, chain)
+		reorg, err := chain.InsertBlock(b, nil)
+		require.Nil(t, err)
+		// the node persists the canonical chain with every block
+		require.Nil(t, storage.CommitReorg(reorg, nil))
+		blocks = append(blocks, b)
+	}
+	require.Nil(t, storage.Close())
+
+	// after a restart every height is available again
+	storage = newStorage(openTestDB(t, dir))
+	defer storage.Close()
+	chain = NewChain(storage.blocks, storage.txs)
+	require.Equal(t, 5, chain.Height())
+	for i, b := range blocks {
+		got, err := chain.GetBlockByHeight(i + 1)
+		require.Nil(t, err)
+		assert.Equal(t, types.HashBlock(b), types.HashBlock(got))
+	}
+
+	// a fork below the tip is followed
+	reorg, err := chain.InsertBlock(childBlock(blocks[2]), nil)
+	require.Nil(t, err)
+	assert.Empty(t, reorg.Added)
+
+	// and the chain continues from the persisted tip
+	require.Nil(t, chain.AddBlock(randomBlock(t, chain)))
+	assert.Equal(t, 6, chain.Height())
+}
+
+func TestNewChainRestoresValidatorSets(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		storage = newStorage(openTestDB(t, dir))
+		chain   = NewChain(storage.blocks, storage.txs)
+		keys    = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		removed = keys[3]
+		g       = &proto.Governance{Action: types.GovernanceRemoveValidator, Validator: removed.Public().Bytes(), EffectiveHeight: 3}
+	)
+	for _, key := range keys[:3] {
+		types.ApproveGovernance(key, g)
+	}
+	chain.SetValidatorSet(types.NewValidatorSet([]*crypto.PublicKey{keys[0].Public(), keys[1].Public(), keys[2].Public(), removed.Public()}))
+
+	// the validator is removed by the second block
+	b1 := randomBlock(t, chain)
+	b1.Transactions = []*proto.Transaction{{Version: 1, Governance: g}}
+	types.SignBlock(keys[0], b1)
+	b2 := childBlock(b1)
+	types.SignBlock(keys[0], b2)
+	for _, b := range []*proto.Block{b1, b2} {
+		reorg, err := chain.InsertBlock(b, nil)
+		require.Nil(t, err)
+		require.Nil(t, storage.CommitReorg(reorg, chain.ValidatorSet()))
+	}
+	require.Nil(t, storage.Close())
+
+	storage = newStorage(openTestDB(t, dir))
+	defer storage.Close()
+	chain = NewChain(storage.blocks, storage.txs)
+	set, err := storage.ValidatorSet()
+	require.Nil(t, err)
+	chain.SetValidatorSet(set)
+
+	// a fork is validated against the validator set of its own parent
+	fork := childBlock(b1)
+	types.SignBlock(removed, fork)
+	reorg, err := chain.InsertBlock(fork, nil)
+	require.Nil(t, err)
+	assert.Empty(t, reorg.Added)
+	fork = childBlock(b2)
+	types.SignBlock(removed, fork)
+	_, err = chain.InsertBlock(fork, nil)
+	assert.ErrorIs(t, err, ErrUnauthorizedProposer)
+}
@@ -0,0 +1,108 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/janrockdev/darkblock/proto"
+	"google.golang.org/grpc"
+)
+
+// syncBatchSize is the number of blocks read from the block store at once
+// while streaming them to a peer.
+const syncBatchSize = 64
+
+// GetBlocks streams the persisted blocks of a range together with their
+// commit certificates.
+func (n *Node) GetBlocks(r *proto.BlockRange, stream grpc.ServerStreamingServer[proto.BlockSearchResult]) error {
+	to := int(r.To)
+	if height := n.storage.Height(); to <= 0 || to > height {
+		to = height
+	}
+	for from := max(int(r.From), 1); from <= to; from += syncBatchSize {
+		results, err := n.storage.ReadBlocks(from, min(from+syncBatchSize-1, to))
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncWith downloads, validates and persists the blocks a peer has beyond the
+// local block store. When the peer is on another branch the download starts
+// further back until it reaches a common block and the fork choice decides.
+func (n *Node) syncWith(c proto.NodeClient) error {
+	n.syncLock.Lock()
+	defer n.syncLock.Unlock()
+
+	from := n.storage.Height() + 1
+	for {
+		synced, err := n.syncFrom(c, from)
+		if errors.Is(err, ErrOrphanBlock) && from > 1 {
+			from = max(from-syncBatchSize, 1)
+			n.Logger.Warn().Msgf("peer is on another branch, syncing from height [%d]", from)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if synced > 0 {
+			n.Logger.Info().Msgf("synced [%d] blocks from height [%d]", synced, from)
+		}
+		return nil
+	}
+}
+
+// syncFrom applies the blocks a peer streams from a height on.
+func (n *Node) syncFrom(c proto.NodeClient, from int) (int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.GetBlocks(ctx, &proto.BlockRange{From: int32(from)})
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return synced, nil
+		}
+		if err != nil {
+			return synced, err
+		}
+		if err := n.applyBlock(res.Block, res.Certificate); err != nil {
+			return synced, fmt.Errorf("block height [%d]: %w", res.Block.GetHeader().GetHeight(), err)
+		}
+		synced++
+	}
+}
+
+// syncPeers fills a gap in the local chain from the connected peers.
+func (n *Node) syncPeers() {
+	n.peerLock.RLock()
+	peers := make([]proto.NodeClient, 0, len(n.peers))
+	for peer := range n.peers {
+		peers = append(peers, peer)
+	}
+	n.peerLock.RUnlock()
+
+	if n.SyncMode == SyncHeaders && len(peers) > 0 {
+		if err := n.syncHeadersFirst(peers); err != nil {
+			n.Logger.Error().Msgf("failed to sync blocks headers first: [%s]", err)
+		}
+		return
+	}
+	for _, peer := range peers {
+		if err := n.syncWith(peer); err != nil {
+			n.Logger.Error().Msgf("failed to sync blocks: [%s]", err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package node
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// hashLen is the length of a block hash, sha3-512.
+const hashLen = 64
+
+// maxClockDrift is how far the timestamp of a block may be ahead of the local clock.
+const maxClockDrift = 15 * time.Second
+
+// The reasons a block is rejected, the returned errors wrap one of them.
+var (
+	ErrNoHeader              = errors.New("block without header")
+	ErrBadSignature          = errors.New("invalid block signature")
+	ErrBadRootHash           = errors.New("root hash does not match the transactions")
+	ErrDuplicateTx           = errors.New("duplicate transaction")
+	ErrBadTransaction        = errors.New("invalid transaction")
+	ErrBadEvidence           = errors.New("invalid block evidence")
+	ErrBadPrevHash           = errors.New("invalid previous block hash")
+	ErrFinalizedConflict     = errors.New("block conflicts with a finalized block")
+	ErrBadHeight             = errors.New("invalid block height")
+	ErrTimestampBeforeParent = errors.New("timestamp before the parent block")
+	ErrTimestampTooFar       = errors.New("timestamp too far in the future")
+	ErrUnauthorizedProposer  = errors.New("proposer is not a validator")
+	ErrBadGovernance         = errors.New("invalid governance")
+	ErrBadCertificate        = errors.New("invalid commit certificate")
+)
+
+// blockRule checks a block on its own, before the chain is looked at.
+type blockRule func(b *proto.Block) error
+
+// parentRule checks a block against the block it extends.
+type parentRule func(parent *blockNode, b *proto.Block) error
+
+var blockRules = []blockRule{
+	checkHeader,
+	checkRootHash,
+	checkSignature,
+	checkPrevHash,
+	checkDuplicateTxs,
+	checkTransactions,
+	checkEvidence,
+}
+
+var parentRules = []parentRule{
+	checkHeight,
+	checkTimestamp,
+	checkProposer,
+	checkGovernance,
+}
+
+func checkHeader(b *proto.Block) error {
+	if b.GetHeader() == nil {
+		return ErrNoHeader
+	}
+	return nil
+}
+
+func checkSignature(b *proto.Block) error {
+	if len(b.PublicKey) != crypto.PubKeyLen || len(b.Signature) != crypto.SignatureLen || !types.VerifyBlock(b) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func checkPrevHash(b *proto.Block) error {
+	if len(b.Header.PrevHash) != hashLen {
+		return fmt.Errorf("%w: [%d] bytes", ErrBadPrevHash, len(b.Header.PrevHash))
+	}
+	return nil
+}
+
+func checkDuplicateTxs(b *proto.Block) error {
+	seen := make(map[string]bool, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		hash := hex.EncodeToString(types.HashTransaction(tx))
+		if seen[hash] {
+			return fmt.Errorf("%w: [%s]", ErrDuplicateTx, hash[:8])
+		}
+		seen[hash] = true
+	}
+	return nil
+}
+
+// checkRootHash verifies the merkle root, blocks without transactions have none.
+func checkRootHash(b *proto.Block) error {
+	if len(b.Transactions) > 0 && !types.VerifyRootHash(b) {
+		return ErrBadRootHash
+	}
+	return nil
+}
+
+func checkTransactions(b *proto.Block) error {
+	for _, tx := range b.Transactions {
+		for _, input := range tx.Inputs {
+			if len(input.Signature) != crypto.SignatureLen || len(input.PublicKey) != crypto.PubKeyLen {
+				return fmt.Errorf("%w: [%s] unsigned input", ErrBadTransaction, hex.EncodeToString(types.HashTransaction(tx))[:8])
+			}
+		}
+		if !types.VerifyTransaction(tx) {
+			return fmt.Errorf("%w: [%s] signature", ErrBadTransaction, hex.EncodeToString(types.HashTransaction(tx))[:8])
+		}
+	}
+	return nil
+}
+
+func checkEvidence(b *proto.Block) error {
+	if !types.VerifyBlockEvidence(b) {
+		return ErrBadEvidence
+	}
+	return nil
+}
+
+func checkHeight(parent *blockNode, b *proto.Block) error {
+	if want := parent.block.Header.Height + 1; b.Header.Height != want {
+		return fmt.Errorf("%w: [%d] where [%d] was expected", ErrBadHeight, b.Header.Height, want)
+	}
+	return nil
+}
+
+func checkTimestamp(parent *blockNode, b *proto.Block) error {
+	if b.Header.Timestamp < parent.block.Header.Timestamp {
+		return ErrTimestampBeforeParent
+	}
+	if ahead := time.Until(time.Unix(0, b.Header.Timestamp)); ahead > maxClockDrift {
+		return fmt.Errorf("%w: [%s] ahead", ErrTimestampTooFar, ahead.Round(time.Second))
+	}
+	return nil
+}
+
+// checkProposer requires the proposer to be in the validator set of the branch,
+// a chain without a validator set accepts any proposer.
+func checkProposer(parent *blockNode, b *proto.Block) error {
+	if parent.validators == nil {
+		return nil
+	}
+	for _, v := range parent.validators.Validators {
+		if string(v) == string(b.PublicKey) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: [%s]", ErrUnauthorizedProposer, hex.EncodeToString(b.PublicKey)[:8])
+}
+
+func checkGovernance(parent *blockNode, b *proto.Block) error {
+	if err := validateGovernance(parent.validators, b); err != nil {
+		return fmt.Errorf("%w: %s", ErrBadGovernance, err)
+	}
+	return nil
+}
+
+// blockStatus turns the reason a block was rejected into a gRPC status.
+func blockStatus(err error) error {
+	var equivocation *EquivocationError
+	code := codes.InvalidArgument
+	switch {
+	case errors.As(err, &equivocation), errors.Is(err, ErrUnauthorizedProposer):
+		code = codes.PermissionDenied
+	case errors.Is(err, ErrFinalizedConflict):
+		code = codes.Aborted
+	case errors.Is(err, ErrOrphanBlock), errors.Is(err, ErrTimestampTooFar):
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,94 @@
+package node
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func signedTx(privKey *crypto.PrivateKey) *proto.Transaction {
+	tx := &proto.Transaction{
+		Version:   1,
+		Timestamp: time.Now().UnixNano(),
+		Inputs:    []*proto.TxInput{{}},
+		Outputs:   []*proto.TxOutput{{Amount: 1, Address: privKey.Public().Address().Bytes()}},
+	}
+	tx.Inputs[0].Signature = types.SignTransaction(privKey, tx).Bytes()
+	tx.Inputs[0].PublicKey = privKey.Public().Bytes()
+	return tx
+}
+
+func TestValidateBlockRules(t *testing.T) {
+	var (
+		validator = crypto.GeneratePrivateKey()
+		outsider  = crypto.GeneratePrivateKey()
+		chain     = NewChain(NewMemoryBlockStore(), NewMemoryTXStore())
+	)
+	chain.SetValidatorSet(types.NewValidatorSet([]*crypto.PublicKey{validator.Public()}))
+	genesis, err := chain.GetBlockByHeight(0)
+	require.Nil(t, err)
+
+	// block builds a valid block on top of genesis, edit changes it before signing
+	block := func(edit func(b *proto.Block), signer *crypto.PrivateKey) *proto.Block {
+		b := &proto.Block{Header: &proto.Header{
+			Version:   1,
+			Height:    genesis.Header.Height + 1,
+			PrevHash:  types.HashBlock(genesis),
+			Timestamp: time.Now().UnixNano(),
+		}}
+		b.Transactions = []*proto.Transaction{signedTx(validator)}
+		edit(b)
+		types.SignBlock(signer, b)
+		return b
+	}
+
+	// signing computes the root hash, a wrong one needs a block signed by hand
+	wrongRoot := block(func(b *proto.Block) {}, validator)
+	wrongRoot.Header.RootHash = util.RandomHash()
+	wrongRoot.Signature = validator.Sign(types.HashBlock(wrongRoot)).Bytes()
+
+	// a broken signature is rejected before anything else
+	tampered := block(func(b *proto.Block) {}, validator)
+	tampered.Header.Version = 2
+
+	tests := []struct {
+		name   string
+		block  *proto.Block
+		err    error
+		status codes.Code
+	}{
+		{"valid", block(func(b *proto.Block) {}, validator), nil, codes.OK},
+		{"empty", block(func(b *proto.Block) { b.Transactions = nil }, validator), nil, codes.OK},
+		{"short prev hash", block(func(b *proto.Block) { b.Header.PrevHash = util.RandomHash() }, validator), ErrBadPrevHash, codes.InvalidArgument},
+		{"unknown parent", block(func(b *proto.Block) { b.Header.PrevHash = types.HashBlock(util.RandomBlock()) }, validator), ErrOrphanBlock, codes.FailedPrecondition},
+		{"duplicate tx", block(func(b *proto.Block) { b.Transactions = append(b.Transactions, b.Transactions[0]) }, validator), ErrDuplicateTx, codes.InvalidArgument},
+		{"root hash", wrongRoot, ErrBadRootHash, codes.InvalidArgument},
+		{"unsigned input", block(func(b *proto.Block) { b.Transactions[0].Inputs[0].Signature = nil }, validator), ErrBadTransaction, codes.InvalidArgument},
+		{"height", block(func(b *proto.Block) { b.Header.Height += 1 }, validator), ErrBadHeight, codes.InvalidArgument},
+		{"timestamp before parent", block(func(b *proto.Block) { b.Header.Timestamp = genesis.Header.Timestamp - 1 }, validator), ErrTimestampBeforeParent, codes.InvalidArgument},
+		{"timestamp too far", block(func(b *proto.Block) { b.Header.Timestamp = time.Now().Add(time.Hour).UnixNano() }, validator), ErrTimestampTooFar, codes.FailedPrecondition},
+		{"unauthorized proposer", block(func(b *proto.Block) {}, outsider), ErrUnauthorizedProposer, codes.PermissionDenied},
+		{"signature", tampered, ErrBadSignature, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := chain.ValidateBlock(tt.block)
+			if tt.err == nil {
+				assert.Nil(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.err), "got [%v]", err)
+			assert.Equal(t, tt.status, status.Code(blockStatus(err)))
+		})
+	}
+}
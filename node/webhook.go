@@ -0,0 +1,597 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/services"
+	"github.com/janrockdev/darkblock/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of a notification body, keyed
+// with the secret of the subscription.
+const SignatureHeader = "X-Darkblock-Signature"
+
+// EventIncluded is the event of a notification for a transaction of a final
+// block.
+const EventIncluded = "included"
+
+// webhookBatch is how many notifications of a subscription are read at once.
+const webhookBatch = 64
+
+// ErrInvalidSubscription is returned for a subscription without a valid url
+// or filter.
+var ErrInvalidSubscription = errors.New("invalid subscription")
+
+// ErrUnknownSubscription is returned for the id of no subscription.
+var ErrUnknownSubscription = errors.New("unknown subscription")
+
+var (
+	subscriptionNamespace = []byte("webhookSub")
+	webhookQueueNamespace = []byte("webhookQueue")
+	deadLetterNamespace   = []byte("webhookDead")
+	webhookSeqKey         = []byte("webhookSeq")
+)
+
+// A failing callback is retried with a backoff from webhookRetryMin up to
+// webhookRetryMax, its notification is a dead letter after webhookMaxAttempts.
+var (
+	webhookRetryMin    = time.Second
+	webhookRetryMax    = 10 * time.Minute
+	webhookMaxAttempts = 10
+	webhookPoll        = time.Second
+	webhookTimeout     = 10 * time.Second
+)
+
+// webhookAllowPrivate lets callbacks reach loopback, private and link-local
+// addresses. It is off so a subscriber cannot make the node call into its own
+// network.
+var webhookAllowPrivate = false
+
+// Notification is the JSON body posted to a callback. TxHash is the hash the
+// sender signed, HashTransactionNoSigPuK, SignedTxHash the hash of the
+// transaction as included. Both find it with GetTransaction.
+type Notification struct {
+	ID           string          `json:"id"` // unique, a retried notification keeps it
+	Subscription string          `json:"subscription"`
+	Event        string          `json:"event"`
+	BlockHeight  int32           `json:"blockHeight"`
+	BlockHash    string          `json:"blockHash"`
+	TxIndex      int             `json:"txIndex"`
+	TxHash       string          `json:"txHash"`
+	SignedTxHash string          `json:"signedTxHash"`
+	Transaction  json.RawMessage `json:"transaction"` // protojson
+}
+
+// webhookDelivery is a queued notification and the state of its retries.
+type webhookDelivery struct {
+	Notification json.RawMessage `json:"notification"`
+	Attempts     int             `json:"attempts"`
+	NextAttempt  int64           `json:"nextAttempt"` // unix nanoseconds
+	LastError    string          `json:"lastError,omitempty"`
+}
+
+// Webhooks notifies the subscribed callbacks of the transactions included in
+// final blocks, the chain never leaves them. Notifications are queued in the
+// transaction committing the blocks as final and posted in order per subscription, a
+// failing callback holds back the later notifications of its subscription
+// until the failing one is delivered or given up as a dead letter.
+type Webhooks struct {
+	db     services.DB
+	client *http.Client
+	notify chan struct{}
+
+	lock sync.RWMutex
+	subs map[string]*proto.Subscription
+}
+
+func newWebhooks(db services.DB) *Webhooks {
+	// the address is checked when the connection is made, after the name of
+	// the callback host was resolved
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: checkCallbackAddr}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Webhooks{
+		db: db,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   webhookTimeout,
+			// a redirect is an answer like any other, it is not followed
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		notify: make(chan struct{}, 1),
+		subs:   map[string]*proto.Subscription{},
+	}
+}
+
+// load reads the subscriptions and drops the notifications left behind by a
+// subscription removed while they were queued.
+func (w *Webhooks) load() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	err := w.db.Iterate(subscriptionNamespace, func(key, value []byte) error {
+		sub := &proto.Subscription{}
+		if err := pb.Unmarshal(value, sub); err != nil {
+			return fmt.Errorf("malformed subscription [%s]: %w", key, err)
+		}
+		w.subs[sub.Id] = sub
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, namespace := range [][]byte{webhookQueueNamespace, deadLetterNamespace} {
+		var orphans [][]byte
+		err := w.db.Scan(namespace, nil, nil, 0, func(key, _ []byte) error {
+			if id, _, _ := strings.Cut(string(key), "_"); w.subs[id] == nil {
+				orphans = append(orphans, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range orphans {
+			if err := w.db.Delete(namespace, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscription and returns it with its id and secret.
+func (w *Webhooks) Subscribe(sub *proto.Subscription) (*proto.Subscription, error) {
+	if err := validateSubscription(sub); err != nil {
+		return nil, err
+	}
+	sub = &proto.Subscription{
+		Id:            randomHex(16),
+		Url:           sub.Url,
+		Address:       sub.Address,
+		PayloadPrefix: sub.PayloadPrefix,
+		Field:         sub.Field,
+		Value:         sub.Value,
+		Secret:        randomHex(32),
+	}
+	subBytes, err := pb.Marshal(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if err := w.db.Put(subscriptionNamespace, []byte(sub.Id), subBytes); err != nil {
+		return nil, err
+	}
+	w.subs[sub.Id] = sub
+	return sub, nil
+}
+
+// Unsubscribe removes a subscription with its queued notifications and dead
+// letters. They are deleted in one transaction before the subscription stops
+// being notified, a failed delete leaves the subscription as it was. The keys
+// are collected without the lock, the commit of a block does not wait for
+// them, a notification queued meanwhile is dropped with the next load.
+func (w *Webhooks) Unsubscribe(id string) error {
+	if w.subscription(id) == nil {
+		return ErrUnknownSubscription
+	}
+	keys := make(map[string][][]byte)
+	for _, namespace := range [][]byte{webhookQueueNamespace, deadLetterNamespace} {
+		err := w.db.Scan(namespace, []byte(id+"_"), nil, 0, func(key, _ []byte) error {
+			keys[string(namespace)] = append(keys[string(namespace)], key)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := w.db.Update(func(txn services.Txn) error {
+		if err := txn.Delete(subscriptionNamespace, []byte(id)); err != nil {
+			return err
+		}
+		for namespace, nsKeys := range keys {
+			for _, key := range nsKeys {
+				if err := txn.Delete([]byte(namespace), key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	delete(w.subs, id)
+	return nil
+}
+
+// DeadLetters returns a page of the notifications of a subscription that were
+// given up, oldest first, and the token of the next page, nil on the last page.
+func (w *Webhooks) DeadLetters(id string, limit int, pageToken []byte) ([]*proto.DeadLetter, []byte, error) {
+	if w.subscription(id) == nil {
+		return nil, nil, ErrUnknownSubscription
+	}
+	prefix := []byte(id + "_")
+	if len(pageToken) > 0 && !bytes.HasPrefix(pageToken, prefix) {
+		return nil, nil, fmt.Errorf("%w: token of another subscription", ErrBadPageToken)
+	}
+
+	var (
+		letters []*proto.DeadLetter
+		next    []byte
+	)
+	err := w.db.Scan(deadLetterNamespace, prefix, pageToken, limit+1, func(key, value []byte) error {
+		if len(letters) == limit {
+			next = key
+			return nil
+		}
+		var d webhookDelivery
+		if err := json.Unmarshal(value, &d); err != nil {
+			return err
+		}
+		letters = append(letters, &proto.DeadLetter{Notification: d.Notification, Attempts: int32(d.Attempts), LastError: d.LastError})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return letters, next, nil
+}
+
+// enqueue queues the notifications of final blocks for the matching
+// subscriptions, in height order.
+func (w *Webhooks) enqueue(txn services.Txn, blocks []*proto.Block) error {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if len(w.subs) == 0 {
+		return nil
+	}
+
+	seq := uint64(0)
+	if ok, err := txn.Has(metaNamespace, webhookSeqKey); err != nil {
+		return err
+	} else if ok {
+		seqBytes, err := txn.Get(metaNamespace, webhookSeqKey)
+		if err != nil {
+			return err
+		}
+		seq, _ = strconv.ParseUint(string(seqBytes), 10, 64)
+	}
+
+	for _, b := range blocks {
+		for i, tx := range b.Transactions {
+			for _, sub := range w.subs {
+				if !matchSubscription(sub, tx) {
+					continue
+				}
+				seq++
+				key := fmt.Sprintf("%s_%020d", sub.Id, seq)
+				body, err := notification(key, sub.Id, EventIncluded, b, i)
+				if err != nil {
+					return err
+				}
+				d, err := json.Marshal(webhookDelivery{Notification: body})
+				if err != nil {
+					return err
+				}
+				if err := txn.Put(webhookQueueNamespace, []byte(key), d); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return txn.Put(metaNamespace, webhookSeqKey, []byte(strconv.FormatUint(seq, 10)))
+}
+
+// wake tells the dispatcher there are new notifications, once they are
+// committed.
+func (w *Webhooks) wake() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run posts the queued notifications until quit is closed.
+func (w *Webhooks) Run(quit <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-quit
+		cancel()
+	}()
+
+	for {
+		more := false
+		for _, sub := range w.subscriptions() {
+			if w.dispatch(ctx, sub) {
+				more = true
+			}
+		}
+		if more {
+			continue
+		}
+		select {
+		case <-quit:
+			return
+		case <-w.notify:
+		case <-time.After(webhookPoll):
+		}
+	}
+}
+
+// dispatch posts the due notifications of a subscription in order until one
+// fails, and returns whether a full batch went through.
+func (w *Webhooks) dispatch(ctx context.Context, sub *proto.Subscription) bool {
+	type queued struct {
+		key []byte
+		d   webhookDelivery
+	}
+	var batch []queued
+	err := w.db.Scan(webhookQueueNamespace, []byte(sub.Id+"_"), nil, webhookBatch, func(key, value []byte) error {
+		var d webhookDelivery
+		if err := json.Unmarshal(value, &d); err != nil {
+			return fmt.Errorf("malformed notification [%s]: %w", key, err)
+		}
+		batch = append(batch, queued{key: key, d: d})
+		return nil
+	})
+	if err != nil {
+		logger.Error().Msgf("failed to read the notifications of subscription [%s]: %v", sub.Id, err)
+		return false
+	}
+
+	for _, q := range batch {
+		if q.d.NextAttempt > time.Now().UnixNano() {
+			return false
+		}
+		err := w.post(ctx, sub, q.d.Notification)
+		if ctx.Err() != nil {
+			return false // stopping, the attempt does not count
+		}
+		if !w.settle(sub, q.key, q.d, err) {
+			return false
+		}
+	}
+	return len(batch) == webhookBatch
+}
+
+// settle records the outcome of posting a notification and returns whether
+// the next one of the subscription can be posted.
+func (w *Webhooks) settle(sub *proto.Subscription, key []byte, d webhookDelivery, err error) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.subs[sub.Id] == nil {
+		return false // unsubscribed meanwhile
+	}
+
+	if err == nil {
+		if err := w.db.Delete(webhookQueueNamespace, key); err != nil {
+			logger.Error().Msgf("failed to remove delivered notification [%s]: %v", key, err)
+			return false
+		}
+		return true
+	}
+
+	d.Attempts++
+	d.LastError = err.Error()
+	if d.Attempts >= webhookMaxAttempts {
+		logger.Warn().Msgf("giving up notification [%s] after [%d] attempts: %v", key, d.Attempts, err)
+		dBytes, err := json.Marshal(d)
+		if err == nil {
+			err = w.db.Update(func(txn services.Txn) error {
+				if err := txn.Put(deadLetterNamespace, key, dBytes); err != nil {
+					return err
+				}
+				return txn.Delete(webhookQueueNamespace, key)
+			})
+		}
+		if err != nil {
+			logger.Error().Msgf("failed to store dead letter [%s]: %v", key, err)
+			return false
+		}
+		return true
+	}
+
+	backoff := webhookRetryMin << (d.Attempts - 1)
+	if backoff <= 0 || backoff > webhookRetryMax {
+		backoff = webhookRetryMax
+	}
+	d.NextAttempt = time.Now().Add(backoff).UnixNano()
+	logger.Warn().Msgf("notification [%s] failed, retrying in [%s]: %v", key, backoff, err)
+	dBytes, err := json.Marshal(d)
+	if err == nil {
+		err = w.db.Put(webhookQueueNamespace, key, dBytes)
+	}
+	if err != nil {
+		logger.Error().Msgf("failed to save the retry of notification [%s]: %v", key, err)
+	}
+	return false
+}
+
+// post sends a notification to the callback of a subscription, any answer but
+// a 2xx is a failure.
+func (w *Webhooks) post(ctx context.Context, sub *proto.Subscription, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, SignNotification(sub.Secret, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("callback answered [%s]", resp.Status)
+	}
+	return nil
+}
+
+// SignNotification returns the signature of a notification body for the
+// secret of its subscription, as sent in SignatureHeader.
+func SignNotification(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhooks) subscription(id string) *proto.Subscription {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.subs[id]
+}
+
+func (w *Webhooks) subscriptions() []*proto.Subscription {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	subs := make([]*proto.Subscription, 0, len(w.subs))
+	for _, sub := range w.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func validateSubscription(sub *proto.Subscription) error {
+	u, err := url.Parse(sub.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: callback url [%s] is not http(s)", ErrInvalidSubscription, sub.Url)
+	}
+	// a host name is checked again once it is resolved, see checkCallbackAddr
+	if !webhookAllowPrivate {
+		host := strings.ToLower(u.Hostname())
+		addr, err := netip.ParseAddr(host)
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") || (err == nil && !publicAddr(addr)) {
+			return fmt.Errorf("%w: callback host [%s] is not public", ErrInvalidSubscription, host)
+		}
+	}
+	if len(sub.Address) == 0 && len(sub.PayloadPrefix) == 0 && sub.Field == "" {
+		return fmt.Errorf("%w: an address, payload prefix or field filter is required", ErrInvalidSubscription)
+	}
+	if len(sub.Address) > 0 && len(sub.Address) != crypto.AddressLen {
+		return fmt.Errorf("%w: address of [%d] bytes", ErrInvalidSubscription, len(sub.Address))
+	}
+	if sub.Value != "" && sub.Field == "" {
+		return fmt.Errorf("%w: value without a field", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+// checkCallbackAddr refuses a connection to an address that is not public,
+// unless webhookAllowPrivate is set.
+func checkCallbackAddr(network, address string, _ syscall.RawConn) error {
+	if webhookAllowPrivate {
+		return nil
+	}
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addr.Addr()) {
+		return fmt.Errorf("callback address [%s] is not public", address)
+	}
+	return nil
+}
+
+// publicAddr reports whether an address is reachable on the internet, it is
+// not loopback, private, link-local, multicast or unspecified.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() && !addr.IsMulticast() && !addr.IsUnspecified()
+}
+
+// matchSubscription tells whether a transaction passes every filter set on a
+// subscription, a field without a value matches any value.
+func matchSubscription(sub *proto.Subscription, tx *proto.Transaction) bool {
+	if len(sub.Address) > 0 {
+		found := false
+		for _, address := range txAddresses(tx) {
+			found = found || bytes.Equal(address, sub.Address)
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(sub.PayloadPrefix) > 0 {
+		found := false
+		for _, output := range tx.Outputs {
+			found = found || bytes.HasPrefix(output.Payload, sub.PayloadPrefix)
+		}
+		if !found {
+			return false
+		}
+	}
+	if sub.Field != "" {
+		found := false
+		for _, output := range tx.Outputs {
+			value, ok := payloadFields(output.Payload, []string{sub.Field})[sub.Field]
+			found = found || (ok && (sub.Value == "" || value == sub.Value))
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// notification encodes the notification of the transaction at a position of
+// a block.
+func notification(id, subID, event string, b *proto.Block, position int) ([]byte, error) {
+	tx := b.Transactions[position]
+	txBytes, err := protojson.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+	keys := txKeys(tx) // the signed hash, then the hash without signature
+	return json.Marshal(Notification{
+		ID:           id,
+		Subscription: subID,
+		Event:        event,
+		BlockHeight:  b.Header.Height,
+		BlockHash:    hex.EncodeToString(types.HashBlock(b)),
+		TxIndex:      position,
+		TxHash:       string(keys[len(keys)-1]),
+		SignedTxHash: string(keys[0]),
+		Transaction:  txBytes,
+	})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
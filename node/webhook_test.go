@@ -0,0 +1,259 @@
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/services"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runWebhooks(t *testing.T, w *Webhooks) {
+	quit, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		w.Run(quit)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		close(quit)
+		<-done
+	})
+}
+
+func TestWebhookNotifications(t *testing.T) {
+	setForTest(t, &webhookRetryMin, time.Millisecond)
+	setForTest(t, &webhookPoll, 5*time.Millisecond)
+	setForTest(t, &webhookAllowPrivate, true)
+	type delivery struct {
+		notification Notification
+		signature    string
+		body         []byte
+	}
+	var (
+		deliveries = make(chan delivery, 4)
+		callback   = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			var n Notification
+			assert.Nil(t, json.Unmarshal(body, &n))
+			deliveries <- delivery{notification: n, signature: r.Header.Get(SignatureHeader), body: body}
+		}))
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		privKey = crypto.GeneratePrivateKey()
+		match   = payloadTx(privKey, `{"metadata": "sims_a"}`)
+		other   = payloadTx(privKey, `{"metadata": "sims_b"}`)
+		genesis = createGenesisBlock()
+		a1      = blockWithTxs(genesis, other, match)
+		b1      = blockWithTxs(genesis)
+		b2      = blockWithTxs(b1, match)
+	)
+	t.Cleanup(callback.Close)
+	t.Cleanup(func() { storage.Close() }) // after the dispatcher stops
+
+	sub, err := storage.webhooks.Subscribe(&proto.Subscription{
+		Url:     callback.URL,
+		Address: privKey.Public().Address().Bytes(),
+		Field:   "metadata",
+		Value:   "sims_a",
+	})
+	require.Nil(t, err)
+	assert.NotEmpty(t, sub.Id)
+	assert.NotEmpty(t, sub.Secret)
+	runWebhooks(t, storage.webhooks)
+
+	// a block is notified once it is final, the abandoned branch never is
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{a1}}, nil))
+	require.Nil(t, storage.CommitReorg(&Reorg{Removed: []*proto.Block{a1}, Added: []*proto.Block{b1, b2}}, nil))
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, deliveries)
+	require.Nil(t, storage.CommitReorg(&Reorg{Finalized: []*proto.Block{b1, b2}}, nil))
+	require.Eventually(t, func() bool { return len(deliveries) == 1 }, time.Second, 5*time.Millisecond)
+
+	d := <-deliveries
+	got := d.notification
+	assert.Equal(t, sub.Id, got.Subscription)
+	assert.Equal(t, EventIncluded, got.Event)
+	assert.Equal(t, int32(2), got.BlockHeight)
+	assert.Equal(t, hex.EncodeToString(types.HashBlock(b2)), got.BlockHash)
+	assert.Equal(t, 0, got.TxIndex)
+	assert.Equal(t, hex.EncodeToString(types.HashTransactionNoSigPuK(match)), got.TxHash)
+	assert.Equal(t, hex.EncodeToString(types.HashTransaction(match)), got.SignedTxHash)
+	assert.Equal(t, SignNotification(sub.Secret, d.body), d.signature)
+	require.Eventually(t, func() bool {
+		return must(storage.db.Len(badgerNamespace(webhookQueueNamespace))) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestWebhookDeadLetters(t *testing.T) {
+	setForTest(t, &webhookRetryMin, time.Millisecond)
+	setForTest(t, &webhookPoll, 5*time.Millisecond)
+	setForTest(t, &webhookMaxAttempts, 3)
+	setForTest(t, &webhookAllowPrivate, true)
+	var (
+		failing    atomic.Bool
+		deliveries = make(chan Notification, 4)
+		callback   = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failing.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var n Notification
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&n))
+			deliveries <- n
+		}))
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		privKey = crypto.GeneratePrivateKey()
+		b1      = blockWithTxs(createGenesisBlock(), payloadTx(privKey, "order:1"))
+		b2      = blockWithTxs(b1, payloadTx(privKey, "order:2"))
+	)
+	t.Cleanup(callback.Close)
+	t.Cleanup(func() { storage.Close() }) // after the dispatcher stops
+	sub, err := storage.webhooks.Subscribe(&proto.Subscription{Url: callback.URL, PayloadPrefix: []byte("order:")})
+	require.Nil(t, err)
+
+	// the callback fails until the notification is given up
+	failing.Store(true)
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b1}, Finalized: []*proto.Block{b1}}, nil))
+	runWebhooks(t, storage.webhooks)
+	require.Eventually(t, func() bool {
+		letters, _, err := storage.webhooks.DeadLetters(sub.Id, 10, nil)
+		return err == nil && len(letters) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	letters, next, err := storage.webhooks.DeadLetters(sub.Id, 10, nil)
+	require.Nil(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, int32(3), letters[0].Attempts)
+	assert.Contains(t, letters[0].LastError, "500")
+	var n Notification
+	require.Nil(t, json.Unmarshal(letters[0].Notification, &n))
+	assert.Equal(t, int32(1), n.BlockHeight)
+
+	// the next notification goes through once the callback is back
+	failing.Store(false)
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b2}, Finalized: []*proto.Block{b2}}, nil))
+	require.Eventually(t, func() bool { return len(deliveries) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), (<-deliveries).BlockHeight)
+
+	_, _, err = storage.webhooks.DeadLetters(sub.Id, 10, []byte("other_"))
+	assert.ErrorIs(t, err, ErrBadPageToken)
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		storage = newStorage(openTestDB(t, dir))
+	)
+
+	for _, sub := range []*proto.Subscription{
+		{Url: "ftp://example.com", Field: "metadata"},
+		{Url: "http://", Field: "metadata"},
+		{Url: "http://example.com"},
+		{Url: "http://example.com", Address: []byte{1, 2, 3}},
+		{Url: "http://example.com", Value: "sims_a"},
+		{Url: "http://127.0.0.1:8080", Field: "metadata"},
+		{Url: "http://localhost/hook", Field: "metadata"},
+		{Url: "http://[::1]/hook", Field: "metadata"},
+		{Url: "http://10.0.0.1/hook", Field: "metadata"},
+		{Url: "http://169.254.169.254/latest", Field: "metadata"},
+	} {
+		_, err := storage.webhooks.Subscribe(sub)
+		assert.ErrorIs(t, err, ErrInvalidSubscription, sub.Url)
+	}
+
+	kept, err := storage.webhooks.Subscribe(&proto.Subscription{Url: "http://example.com/a", Field: "metadata"})
+	require.Nil(t, err)
+	removed, err := storage.webhooks.Subscribe(&proto.Subscription{Url: "http://example.com/b", Field: "metadata"})
+	require.Nil(t, err)
+	b1 := blockWithTxs(createGenesisBlock(), payloadTx(crypto.GeneratePrivateKey(), `{"metadata": 1}`))
+	require.Nil(t, storage.CommitReorg(&Reorg{Added: []*proto.Block{b1}, Finalized: []*proto.Block{b1}}, nil))
+	assert.Equal(t, 2, int(must(storage.db.Len(badgerNamespace(webhookQueueNamespace)))))
+
+	// removing a subscription drops its notifications
+	require.Nil(t, storage.webhooks.Unsubscribe(removed.Id))
+	assert.ErrorIs(t, storage.webhooks.Unsubscribe(removed.Id), ErrUnknownSubscription)
+	_, _, err = storage.webhooks.DeadLetters(removed.Id, 10, nil)
+	assert.ErrorIs(t, err, ErrUnknownSubscription)
+	assert.Equal(t, 1, int(must(storage.db.Len(badgerNamespace(webhookQueueNamespace)))))
+	require.Nil(t, storage.Close())
+
+	// subscriptions are kept across restarts
+	storage = newStorage(openTestDB(t, dir))
+	defer storage.Close()
+	require.Nil(t, storage.webhooks.load())
+	assert.Equal(t, kept.Secret, storage.webhooks.subscription(kept.Id).Secret)
+	assert.Nil(t, storage.webhooks.subscription(removed.Id))
+}
+
+// failingUpdates fails every transaction of the database.
+type failingUpdates struct {
+	services.DB
+}
+
+func (failingUpdates) Update(fn func(txn services.Txn) error) error {
+	return errors.New("disk full")
+}
+
+func TestWebhookUnsubscribeFailure(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+	defer db.Close()
+	webhooks := newWebhooks(db)
+	sub, err := webhooks.Subscribe(&proto.Subscription{Url: "http://example.com", Field: "metadata"})
+	require.Nil(t, err)
+
+	// a subscription whose keys could not be deleted is still notified
+	webhooks.db = failingUpdates{db}
+	assert.Error(t, webhooks.Unsubscribe(sub.Id))
+	assert.NotNil(t, webhooks.subscription(sub.Id))
+
+	webhooks.db = db
+	require.Nil(t, webhooks.Unsubscribe(sub.Id))
+	assert.Nil(t, webhooks.subscription(sub.Id))
+	require.Nil(t, webhooks.load())
+	assert.Nil(t, webhooks.subscription(sub.Id))
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestWebhookCallbackAddresses(t *testing.T) {
+	var (
+		called   atomic.Bool
+		callback = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called.Store(true)
+		}))
+		storage = newStorage(openTestDB(t, t.TempDir()))
+		sub     = &proto.Subscription{Url: callback.URL, Secret: "secret"}
+	)
+	defer callback.Close()
+	defer storage.Close()
+
+	// a host resolving to a private address is refused when the node connects
+	err := storage.webhooks.post(context.Background(), sub, []byte("{}"))
+	assert.ErrorContains(t, err, "is not public")
+	assert.False(t, called.Load())
+
+	// a redirect is not followed
+	setForTest(t, &webhookAllowPrivate, true)
+	redirect := httptest.NewServer(http.RedirectHandler(callback.URL, http.StatusFound))
+	defer redirect.Close()
+	sub.Url = redirect.URL
+	err = storage.webhooks.post(context.Background(), sub, []byte("{}"))
+	assert.ErrorContains(t, err, "302")
+	assert.False(t, called.Load())
+}
@@ -19,11 +19,22 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Node_Handshake_FullMethodName         = "/Node/Handshake"
-	Node_HandleTransaction_FullMethodName = "/Node/HandleTransaction"
-	Node_HandleBlock_FullMethodName       = "/Node/HandleBlock"
-	Node_GetBlock_FullMethodName          = "/Node/GetBlock"
-	Node_GetTransaction_FullMethodName    = "/Node/GetTransaction"
+	Node_Handshake_FullMethodName                 = "/Node/Handshake"
+	Node_HandleTransaction_FullMethodName         = "/Node/HandleTransaction"
+	Node_HandleBlock_FullMethodName               = "/Node/HandleBlock"
+	Node_HandleCertifiedBlock_FullMethodName      = "/Node/HandleCertifiedBlock"
+	Node_GetBlock_FullMethodName                  = "/Node/GetBlock"
+	Node_GetTransaction_FullMethodName            = "/Node/GetTransaction"
+	Node_ListTransactionsByAddress_FullMethodName = "/Node/ListTransactionsByAddress"
+	Node_SearchByPayload_FullMethodName           = "/Node/SearchByPayload"
+	Node_Subscribe_FullMethodName                 = "/Node/Subscribe"
+	Node_Unsubscribe_FullMethodName               = "/Node/Unsubscribe"
+	Node_ListDeadLetters_FullMethodName           = "/Node/ListDeadLetters"
+	Node_HandleConsensusMessage_FullMethodName    = "/Node/HandleConsensusMessage"
+	Node_HandleRaftMessage_FullMethodName         = "/Node/HandleRaftMessage"
+	Node_HandleEvidence_FullMethodName            = "/Node/HandleEvidence"
+	Node_GetBlocks_FullMethodName                 = "/Node/GetBlocks"
+	Node_GetHeaders_FullMethodName                = "/Node/GetHeaders"
 )
 
 // NodeClient is the client API for Node service.
@@ -33,8 +44,19 @@ type NodeClient interface {
 	Handshake(ctx context.Context, in *Version, opts ...grpc.CallOption) (*Version, error)
 	HandleTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*Ack, error)
 	HandleBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Ack, error)
+	HandleCertifiedBlock(ctx context.Context, in *BlockSearchResult, opts ...grpc.CallOption) (*Ack, error)
 	GetBlock(ctx context.Context, in *BlockSearch, opts ...grpc.CallOption) (*BlockSearchResult, error)
 	GetTransaction(ctx context.Context, in *TxSearch, opts ...grpc.CallOption) (*TxSearchResult, error)
+	ListTransactionsByAddress(ctx context.Context, in *AddressSearch, opts ...grpc.CallOption) (*AddressSearchResult, error)
+	SearchByPayload(ctx context.Context, in *PayloadSearch, opts ...grpc.CallOption) (*PayloadSearchResult, error)
+	Subscribe(ctx context.Context, in *Subscription, opts ...grpc.CallOption) (*Subscription, error)
+	Unsubscribe(ctx context.Context, in *Subscription, opts ...grpc.CallOption) (*Ack, error)
+	ListDeadLetters(ctx context.Context, in *DeadLetterSearch, opts ...grpc.CallOption) (*DeadLetterResult, error)
+	HandleConsensusMessage(ctx context.Context, in *ConsensusMessage, opts ...grpc.CallOption) (*Ack, error)
+	HandleRaftMessage(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*Ack, error)
+	HandleEvidence(ctx context.Context, in *Evidence, opts ...grpc.CallOption) (*Ack, error)
+	GetBlocks(ctx context.Context, in *BlockRange, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlockSearchResult], error)
+	GetHeaders(ctx context.Context, in *BlockRange, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Block], error)
 }
 
 type nodeClient struct {
@@ -75,6 +97,16 @@ func (c *nodeClient) HandleBlock(ctx context.Context, in *Block, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *nodeClient) HandleCertifiedBlock(ctx context.Context, in *BlockSearchResult, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, Node_HandleCertifiedBlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *nodeClient) GetBlock(ctx context.Context, in *BlockSearch, opts ...grpc.CallOption) (*BlockSearchResult, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(BlockSearchResult)
@@ -95,6 +127,124 @@ func (c *nodeClient) GetTransaction(ctx context.Context, in *TxSearch, opts ...g
 	return out, nil
 }
 
+func (c *nodeClient) ListTransactionsByAddress(ctx context.Context, in *AddressSearch, opts ...grpc.CallOption) (*AddressSearchResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddressSearchResult)
+	err := c.cc.Invoke(ctx, Node_ListTransactionsByAddress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) SearchByPayload(ctx context.Context, in *PayloadSearch, opts ...grpc.CallOption) (*PayloadSearchResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PayloadSearchResult)
+	err := c.cc.Invoke(ctx, Node_SearchByPayload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) Subscribe(ctx context.Context, in *Subscription, opts ...grpc.CallOption) (*Subscription, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Subscription)
+	err := c.cc.Invoke(ctx, Node_Subscribe_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) Unsubscribe(ctx context.Context, in *Subscription, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, Node_Unsubscribe_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) ListDeadLetters(ctx context.Context, in *DeadLetterSearch, opts ...grpc.CallOption) (*DeadLetterResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeadLetterResult)
+	err := c.cc.Invoke(ctx, Node_ListDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) HandleConsensusMessage(ctx context.Context, in *ConsensusMessage, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, Node_HandleConsensusMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) HandleRaftMessage(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, Node_HandleRaftMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) HandleEvidence(ctx context.Context, in *Evidence, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, Node_HandleEvidence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) GetBlocks(ctx context.Context, in *BlockRange, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlockSearchResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Node_ServiceDesc.Streams[0], Node_GetBlocks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BlockRange, BlockSearchResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Node_GetBlocksClient = grpc.ServerStreamingClient[BlockSearchResult]
+
+func (c *nodeClient) GetHeaders(ctx context.Context, in *BlockRange, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Block], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Node_ServiceDesc.Streams[1], Node_GetHeaders_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BlockRange, Block]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Node_GetHeadersClient = grpc.ServerStreamingClient[Block]
+
 // NodeServer is the server API for Node service.
 // All implementations must embed UnimplementedNodeServer
 // for forward compatibility.
@@ -102,8 +252,19 @@ type NodeServer interface {
 	Handshake(context.Context, *Version) (*Version, error)
 	HandleTransaction(context.Context, *Transaction) (*Ack, error)
 	HandleBlock(context.Context, *Block) (*Ack, error)
+	HandleCertifiedBlock(context.Context, *BlockSearchResult) (*Ack, error)
 	GetBlock(context.Context, *BlockSearch) (*BlockSearchResult, error)
 	GetTransaction(context.Context, *TxSearch) (*TxSearchResult, error)
+	ListTransactionsByAddress(context.Context, *AddressSearch) (*AddressSearchResult, error)
+	SearchByPayload(context.Context, *PayloadSearch) (*PayloadSearchResult, error)
+	Subscribe(context.Context, *Subscription) (*Subscription, error)
+	Unsubscribe(context.Context, *Subscription) (*Ack, error)
+	ListDeadLetters(context.Context, *DeadLetterSearch) (*DeadLetterResult, error)
+	HandleConsensusMessage(context.Context, *ConsensusMessage) (*Ack, error)
+	HandleRaftMessage(context.Context, *RaftMessage) (*Ack, error)
+	HandleEvidence(context.Context, *Evidence) (*Ack, error)
+	GetBlocks(*BlockRange, grpc.ServerStreamingServer[BlockSearchResult]) error
+	GetHeaders(*BlockRange, grpc.ServerStreamingServer[Block]) error
 	mustEmbedUnimplementedNodeServer()
 }
 
@@ -123,12 +284,45 @@ func (UnimplementedNodeServer) HandleTransaction(context.Context, *Transaction)
 func (UnimplementedNodeServer) HandleBlock(context.Context, *Block) (*Ack, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HandleBlock not implemented")
 }
+func (UnimplementedNodeServer) HandleCertifiedBlock(context.Context, *BlockSearchResult) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleCertifiedBlock not implemented")
+}
 func (UnimplementedNodeServer) GetBlock(context.Context, *BlockSearch) (*BlockSearchResult, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBlock not implemented")
 }
 func (UnimplementedNodeServer) GetTransaction(context.Context, *TxSearch) (*TxSearchResult, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
 }
+func (UnimplementedNodeServer) ListTransactionsByAddress(context.Context, *AddressSearch) (*AddressSearchResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTransactionsByAddress not implemented")
+}
+func (UnimplementedNodeServer) SearchByPayload(context.Context, *PayloadSearch) (*PayloadSearchResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchByPayload not implemented")
+}
+func (UnimplementedNodeServer) Subscribe(context.Context, *Subscription) (*Subscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedNodeServer) Unsubscribe(context.Context, *Subscription) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unsubscribe not implemented")
+}
+func (UnimplementedNodeServer) ListDeadLetters(context.Context, *DeadLetterSearch) (*DeadLetterResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedNodeServer) HandleConsensusMessage(context.Context, *ConsensusMessage) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleConsensusMessage not implemented")
+}
+func (UnimplementedNodeServer) HandleRaftMessage(context.Context, *RaftMessage) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleRaftMessage not implemented")
+}
+func (UnimplementedNodeServer) HandleEvidence(context.Context, *Evidence) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleEvidence not implemented")
+}
+func (UnimplementedNodeServer) GetBlocks(*BlockRange, grpc.ServerStreamingServer[BlockSearchResult]) error {
+	return status.Errorf(codes.Unimplemented, "method GetBlocks not implemented")
+}
+func (UnimplementedNodeServer) GetHeaders(*BlockRange, grpc.ServerStreamingServer[Block]) error {
+	return status.Errorf(codes.Unimplemented, "method GetHeaders not implemented")
+}
 func (UnimplementedNodeServer) mustEmbedUnimplementedNodeServer() {}
 func (UnimplementedNodeServer) testEmbeddedByValue()              {}
 
@@ -204,6 +398,24 @@ func _Node_HandleBlock_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Node_HandleCertifiedBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockSearchResult)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleCertifiedBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_HandleCertifiedBlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleCertifiedBlock(ctx, req.(*BlockSearchResult))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Node_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(BlockSearch)
 	if err := dec(in); err != nil {
@@ -240,6 +452,172 @@ func _Node_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Node_ListTransactionsByAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddressSearch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).ListTransactionsByAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_ListTransactionsByAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).ListTransactionsByAddress(ctx, req.(*AddressSearch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_SearchByPayload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PayloadSearch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).SearchByPayload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_SearchByPayload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).SearchByPayload(ctx, req.(*PayloadSearch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_Subscribe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Subscription)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).Subscribe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_Subscribe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).Subscribe(ctx, req.(*Subscription))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_Unsubscribe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Subscription)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).Unsubscribe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_Unsubscribe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).Unsubscribe(ctx, req.(*Subscription))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterSearch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_ListDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).ListDeadLetters(ctx, req.(*DeadLetterSearch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_HandleConsensusMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConsensusMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleConsensusMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_HandleConsensusMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleConsensusMessage(ctx, req.(*ConsensusMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_HandleRaftMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaftMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleRaftMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_HandleRaftMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleRaftMessage(ctx, req.(*RaftMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_HandleEvidence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Evidence)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).HandleEvidence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Node_HandleEvidence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).HandleEvidence(ctx, req.(*Evidence))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_GetBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlockRange)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NodeServer).GetBlocks(m, &grpc.GenericServerStream[BlockRange, BlockSearchResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Node_GetBlocksServer = grpc.ServerStreamingServer[BlockSearchResult]
+
+func _Node_GetHeaders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlockRange)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NodeServer).GetHeaders(m, &grpc.GenericServerStream[BlockRange, Block]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Node_GetHeadersServer = grpc.ServerStreamingServer[Block]
+
 // Node_ServiceDesc is the grpc.ServiceDesc for Node service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -259,6 +637,10 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "HandleBlock",
 			Handler:    _Node_HandleBlock_Handler,
 		},
+		{
+			MethodName: "HandleCertifiedBlock",
+			Handler:    _Node_HandleCertifiedBlock_Handler,
+		},
 		{
 			MethodName: "GetBlock",
 			Handler:    _Node_GetBlock_Handler,
@@ -267,7 +649,50 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetTransaction",
 			Handler:    _Node_GetTransaction_Handler,
 		},
+		{
+			MethodName: "ListTransactionsByAddress",
+			Handler:    _Node_ListTransactionsByAddress_Handler,
+		},
+		{
+			MethodName: "SearchByPayload",
+			Handler:    _Node_SearchByPayload_Handler,
+		},
+		{
+			MethodName: "Subscribe",
+			Handler:    _Node_Subscribe_Handler,
+		},
+		{
+			MethodName: "Unsubscribe",
+			Handler:    _Node_Unsubscribe_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _Node_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "HandleConsensusMessage",
+			Handler:    _Node_HandleConsensusMessage_Handler,
+		},
+		{
+			MethodName: "HandleRaftMessage",
+			Handler:    _Node_HandleRaftMessage_Handler,
+		},
+		{
+			MethodName: "HandleEvidence",
+			Handler:    _Node_HandleEvidence_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetBlocks",
+			Handler:       _Node_GetBlocks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetHeaders",
+			Handler:       _Node_GetHeaders_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/types.proto",
 }
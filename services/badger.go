@@ -24,16 +24,31 @@ type (
 	DB interface {
 		Get(namespace, key []byte) (value []byte, err error)
 		GetByNumber(namespace []byte, number int64) (value []byte, err error)
+		GetByHeight(namespace []byte, height int64) (value []byte, err error)
 		GetLatestRecord() (value []byte, prefix int64, hash []byte, err error)
 		GetRecoveryFromCache(nameSpace []byte) (lastBlockHash []byte, lastBlockHeight int32, lastTxHash []byte, lastSignature []byte, lastPublicKey []byte, err error)
 		Set(namespace, keyHash []byte, keyHeight int64, value []byte) error
+		Put(namespace, key, value []byte) error
 		Has(namespace, key []byte) (bool, error)
+		Delete(namespace, key []byte) error
+		Update(fn func(txn Txn) error) error
+		Iterate(namespace []byte, fn func(key, value []byte) error) error
+		Scan(namespace, prefix, start []byte, limit int, fn func(key, value []byte) error) error
 		Size(namespace []byte) (int64, error)
 		Len(namespace []byte) (int64, error)
 		RecordExists() (bool, error)
 		Close() error
 	}
 
+	// Txn is a read-write transaction, the writes of an Update are committed
+	// together or not at all.
+	Txn interface {
+		Get(namespace, key []byte) (value []byte, err error)
+		Has(namespace, key []byte) (bool, error)
+		Put(namespace, key, value []byte) error
+		Delete(namespace, key []byte) error
+	}
+
 	BadgerDB struct {
 		db         *badger.DB
 		ctx        context.Context
@@ -197,6 +212,124 @@ func (bdb *BadgerDB) Set(namespace, keyHash []byte, keyHeight int64, value []byt
 	return nil
 }
 
+// Put stores a value under a plain namespaced key, without the height prefix used by Set.
+func (bdb *BadgerDB) Put(namespace, key, value []byte) error {
+	err := bdb.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(badgerNamespaceKey(namespace, key), value)
+	})
+	if err != nil {
+		util.Logger.Debug().Msgf("failed to put key %s for namespace %s: %v", key, namespace, err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes a value stored by Put.
+func (bdb *BadgerDB) Delete(namespace, key []byte) error {
+	err := bdb.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(badgerNamespaceKey(namespace, key))
+	})
+	if err != nil {
+		util.Logger.Debug().Msgf("failed to delete key %s for namespace %s: %v", key, namespace, err)
+		return err
+	}
+
+	return nil
+}
+
+// Update runs fn in one transaction and commits its writes when fn returns nil.
+func (bdb *BadgerDB) Update(fn func(txn Txn) error) error {
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		return fn(badgerTxn{txn})
+	})
+}
+
+type badgerTxn struct {
+	txn *badger.Txn
+}
+
+func (t badgerTxn) Get(namespace, key []byte) ([]byte, error) {
+	item, err := t.txn.Get(badgerNamespaceKey(namespace, key))
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (t badgerTxn) Has(namespace, key []byte) (bool, error) {
+	_, err := t.txn.Get(badgerNamespaceKey(namespace, key))
+	switch err {
+	case badger.ErrKeyNotFound:
+		return false, nil
+	case nil:
+		return true, nil
+	}
+	return false, err
+}
+
+func (t badgerTxn) Put(namespace, key, value []byte) error {
+	return t.txn.Set(badgerNamespaceKey(namespace, key), value)
+}
+
+func (t badgerTxn) Delete(namespace, key []byte) error {
+	return t.txn.Delete(badgerNamespaceKey(namespace, key))
+}
+
+// Iterate calls fn for the records of a namespace in key order, the key is
+// passed without the namespace. Records stored by Set come in height order.
+func (bdb *BadgerDB) Iterate(namespace []byte, fn func(key, value []byte) error) error {
+	return bdb.db.View(func(txn *badger.Txn) error {
+		prefix := badgerNamespaceKey(namespace, nil)
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err := fn(it.Item().KeyCopy(nil)[len(prefix):], value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Scan calls fn for at most limit records of a namespace whose keys start with
+// prefix, in key order beginning at start. A nil start begins at the prefix and
+// a limit of 0 scans every record. The key is passed without the namespace.
+func (bdb *BadgerDB) Scan(namespace, prefix, start []byte, limit int, fn func(key, value []byte) error) error {
+	return bdb.db.View(func(txn *badger.Txn) error {
+		ns := badgerNamespaceKey(namespace, nil)
+		full := badgerNamespaceKey(namespace, prefix)
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = full
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		seek := full
+		if start != nil {
+			seek = badgerNamespaceKey(namespace, start)
+		}
+		n := 0
+		for it.Seek(seek); it.ValidForPrefix(full) && (limit == 0 || n < limit); it.Next() {
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err := fn(it.Item().KeyCopy(nil)[len(ns):], value); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+}
+
 func (bdb *BadgerDB) Has(namespace, key []byte) (ok bool, err error) {
 	_, err = bdb.Get(namespace, key)
 	switch err {
@@ -233,18 +366,41 @@ func (bdb *BadgerDB) GetByNumber(namespace []byte, index int64) (value []byte, e
 	return value, err
 }
 
+// GetByHeight returns the record stored by Set under the given height.
+func (bdb *BadgerDB) GetByHeight(namespace []byte, height int64) (value []byte, err error) {
+	err = bdb.db.View(func(txn *badger.Txn) error {
+		prefix := badgerNamespaceKey(namespace, []byte(fmt.Sprintf("%016d_", height)))
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		it.Seek(prefix)
+		if !it.ValidForPrefix(prefix) {
+			return fmt.Errorf("height %d not found in namespace %s", height, namespace)
+		}
+		value, err = it.Item().ValueCopy(nil)
+		return err
+	})
+
+	return value, err
+}
+
 func (bdb *BadgerDB) GetLatestRecord() (value []byte, prefix int64, hash []byte, err error) {
 
 	err = bdb.db.View(func(txn *badger.Txn) error {
+		// only blocks are considered, other namespaces share the same keyspace
+		namespace := badgerNamespaceKey([]byte("blockStore"), nil)
 		opts := badger.DefaultIteratorOptions
 		opts.Reverse = true
+		opts.Prefix = namespace
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
 		// Move to the end of the key range.
-		it.Rewind()
+		it.Seek(append(namespace, 0xff))
 
-		if !it.Valid() {
+		if !it.ValidForPrefix(namespace) {
 			return badger.ErrKeyNotFound
 		}
 
@@ -41,12 +41,14 @@ func NewCouchbaseService(connectionString, username, password, blockBucketName,
 	}, nil
 }
 
+// Name names the service as a block sink.
+func (cs *CouchbaseService) Name() string {
+	return "couchbase"
+}
+
 func (cs *CouchbaseService) StoreBlock(block *proto.Block) error {
 
-	blockPrefix := fmt.Sprintf("%016d", block.Header.Height)
-	blockHash := hex.EncodeToString(types.HashBlock(block))
-	blockKey := fmt.Sprintf("%s_%s", blockPrefix, blockHash)
-	blockID := fmt.Sprintf("block::%s", blockKey)
+	blockID := couchbaseBlockID(block)
 
 	_, err := cs.blockColl.Upsert(blockID, block, &gocb.UpsertOptions{})
 	if err != nil {
@@ -64,14 +66,22 @@ func (cs *CouchbaseService) StoreBlock(block *proto.Block) error {
 	return nil
 }
 
+// couchbaseBlockID is the document id of a block, block::height_hash.
+func couchbaseBlockID(block *proto.Block) string {
+	blockPrefix := fmt.Sprintf("%016d", block.Header.Height)
+	blockHash := hex.EncodeToString(types.HashBlock(block))
+	return fmt.Sprintf("block::%s_%s", blockPrefix, blockHash)
+}
+
 func (cs *CouchbaseService) SearchTransactionByPayload(payload []byte) (string, error) {
-	// N1QL query to search for the transaction by its payload.
-	query := fmt.Sprintf("SELECT META(tx).id, tx FROM `transactions` AS tx WHERE tx.outputs[0].payload = \"%s\"", payload)
+	// N1QL query to search for the transaction by its payload, the payload is
+	// passed as a parameter.
+	query := "SELECT META(tx).id, tx FROM `transactions` AS tx WHERE tx.outputs[0].payload = $payload"
 
 	// Use the cluster to execute the N1QL query.
 	rows, err := cs.cluster.Query(query, &gocb.QueryOptions{
 		NamedParameters: map[string]interface{}{
-			"$1": payload,
+			"payload": string(payload), // bound, never part of the statement
 		},
 	})
 	if err != nil {
@@ -100,12 +110,12 @@ func (cs *CouchbaseService) SearchTransactionByPayload(payload []byte) (string,
 
 func (cs *CouchbaseService) SearchBlockByPayload(payload []byte) (string, error) {
 	// N1QL query to search for the transaction by its payload.
-	query := fmt.Sprintf("SELECT META(bx).id, bx, tx, output FROM `blocks` AS bx UNNEST bx.transactions AS tx UNNEST tx.outputs AS output WHERE output.payload = \"%s\"", payload)
+	query := "SELECT META(bx).id, bx, tx, output FROM `blocks` AS bx UNNEST bx.transactions AS tx UNNEST tx.outputs AS output WHERE output.payload = $payload"
 
 	// Use the cluster to execute the N1QL query.
 	rows, err := cs.cluster.Query(query, &gocb.QueryOptions{
 		NamedParameters: map[string]interface{}{
-			"$1": payload,
+			"payload": string(payload), // bound, never part of the statement
 		},
 	})
 	if err != nil {
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Segment files are named after the height of their first block. The segment
+// being written carries partialSuffix until it is rolled.
+const (
+	segmentData   = ".jsonl.gz"
+	segmentIndex  = ".index.jsonl"
+	partialSuffix = ".partial"
+)
+
+// SegmentEntry is a line of a segment index.
+type SegmentEntry struct {
+	Height int32  `json:"height"`
+	Hash   string `json:"hash"`
+	Line   int    `json:"line"` // line of the block in the segment, from 0
+	Txs    int    `json:"txs"`
+}
+
+// FileSink exports final blocks as rolling, gzip compressed JSON Lines
+// segments, a block per line in the protojson encoding, each with an index
+// file. Every block is flushed to disk before it is acknowledged, the segment
+// of a crashed process is recovered up to its last indexed line when the sink
+// is opened.
+type FileSink struct {
+	dir       string
+	maxBlocks int   // blocks per segment
+	maxBytes  int64 // uncompressed bytes per segment
+
+	lock       sync.Mutex
+	seg        *segment // nil until the first block of a segment
+	lastHeight int32    // last exported block, redeliveries up to it are skipped
+}
+
+type segment struct {
+	name  string // path without the suffixes
+	data  *os.File
+	zip   *gzip.Writer
+	index *os.File
+	lines int
+	bytes int64
+}
+
+// NewFileSink opens a file sink writing segments to dir.
+func NewFileSink(dir string, maxBlocks int, maxBytes int64) (*FileSink, error) {
+	if maxBlocks <= 0 || maxBytes <= 0 {
+		return nil, fmt.Errorf("invalid segment size of [%d] blocks and [%d] bytes", maxBlocks, maxBytes)
+	}
+	if err := os.MkdirAll(dir, 0774); err != nil {
+		return nil, err
+	}
+	fs := &FileSink{dir: dir, maxBlocks: maxBlocks, maxBytes: maxBytes}
+	if err := fs.recover(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Name names the file sink as a block sink.
+func (fs *FileSink) Name() string {
+	return "file"
+}
+
+// StoreBlock appends a block to the open segment, blocks arrive in height
+// order.
+func (fs *FileSink) StoreBlock(block *proto.Block) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if block.Header.Height <= fs.lastHeight {
+		return nil
+	}
+	line, err := protojson.Marshal(block)
+	if err != nil {
+		return err
+	}
+	seg, err := fs.segment(block.Header.Height)
+	if err != nil {
+		return err
+	}
+	entry := SegmentEntry{Height: block.Header.Height, Hash: hex.EncodeToString(types.HashBlock(block)), Line: seg.lines, Txs: len(block.Transactions)}
+	if err := seg.write(append(line, '\n'), entry); err != nil {
+		// the segment is cut back to its indexed lines, the retry of the
+		// block starts a new one
+		fs.seg = nil
+		seg.zip.Close()
+		seg.data.Close()
+		seg.index.Close()
+		if _, recoverErr := recoverSegment(seg.name); recoverErr != nil {
+			return errors.Join(err, recoverErr)
+		}
+		return err
+	}
+	fs.lastHeight = block.Header.Height
+
+	if seg.lines >= fs.maxBlocks || seg.bytes >= fs.maxBytes {
+		return fs.roll()
+	}
+	return nil
+}
+
+// Close rolls the open segment.
+func (fs *FileSink) Close() error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	return fs.roll()
+}
+
+// segment returns the open segment, a new one starts at height.
+func (fs *FileSink) segment(height int32) (*segment, error) {
+	if fs.seg != nil {
+		return fs.seg, nil
+	}
+	name := filepath.Join(fs.dir, fmt.Sprintf("blocks-%016d", height))
+	data, err := os.OpenFile(name+segmentData+partialSuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0664)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(name+segmentIndex+partialSuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0664)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	fs.seg = &segment{name: name, data: data, zip: gzip.NewWriter(data), index: index}
+	return fs.seg, nil
+}
+
+// roll completes the open segment and gives it its final name.
+func (fs *FileSink) roll() error {
+	if fs.seg == nil {
+		return nil
+	}
+	seg := fs.seg
+	fs.seg = nil
+
+	if err := seg.zip.Close(); err != nil {
+		return err
+	}
+	for _, f := range []*os.File{seg.data, seg.index} {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return completeSegment(seg.name)
+}
+
+// write appends a block line and its index entry and flushes both.
+func (seg *segment) write(line []byte, entry SegmentEntry) error {
+	if _, err := seg.zip.Write(line); err != nil {
+		return err
+	}
+	if err := seg.zip.Flush(); err != nil {
+		return err
+	}
+	if err := seg.data.Sync(); err != nil {
+		return err
+	}
+	seg.lines++
+	seg.bytes += int64(len(line))
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := seg.index.Write(append(entryBytes, '\n')); err != nil {
+		return err
+	}
+	return seg.index.Sync()
+}
+
+// recover completes the segments a crashed process left open, keeping their
+// indexed lines, and finds the last exported block.
+func (fs *FileSink) recover() error {
+	partials, err := filepath.Glob(filepath.Join(fs.dir, "blocks-*"+segmentData+partialSuffix))
+	if err != nil {
+		return err
+	}
+	for _, partial := range partials {
+		name := strings.TrimSuffix(partial, segmentData+partialSuffix)
+		lines, err := recoverSegment(name)
+		if err != nil {
+			return fmt.Errorf("failed to recover segment [%s]: %w", name, err)
+		}
+		util.Logger.Warn().Msgf("recovered [%d] blocks of unfinished segment [%s]", lines, name)
+	}
+
+	indexes, err := filepath.Glob(filepath.Join(fs.dir, "blocks-*"+segmentIndex))
+	if err != nil || len(indexes) == 0 {
+		return err
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return segmentHeight(indexes[i]) < segmentHeight(indexes[j])
+	})
+	entries, err := readSegmentIndex(indexes[len(indexes)-1])
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		fs.lastHeight = entries[len(entries)-1].Height
+	}
+	return nil
+}
+
+// recoverSegment rewrites an unfinished segment with its complete lines that
+// have an index entry, a line written just before a crash is dropped with its
+// block exported again, and returns the number of lines kept. A segment left
+// without lines is removed.
+func recoverSegment(name string) (int, error) {
+	lines, err := readCompleteLines(name + segmentData + partialSuffix)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := readSegmentIndex(name + segmentIndex + partialSuffix)
+	if err != nil {
+		return 0, err
+	}
+	kept := 0
+	for _, entry := range entries {
+		if entry.Line != kept || kept == len(lines) {
+			break
+		}
+		kept++
+	}
+	lines, entries = lines[:kept], entries[:kept]
+	if kept == 0 {
+		if err := os.Remove(name + segmentData + partialSuffix); err != nil {
+			return 0, err
+		}
+		return 0, os.Remove(name + segmentIndex + partialSuffix)
+	}
+
+	data, err := os.Create(name + segmentData + partialSuffix + ".tmp")
+	if err != nil {
+		return 0, err
+	}
+	zip := gzip.NewWriter(data)
+	for _, line := range lines {
+		if _, err := zip.Write(line); err != nil {
+			data.Close()
+			return 0, err
+		}
+	}
+	if err := zip.Close(); err != nil {
+		data.Close()
+		return 0, err
+	}
+	if err := data.Sync(); err != nil {
+		data.Close()
+		return 0, err
+	}
+	if err := data.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(name+segmentData+partialSuffix+".tmp", name+segmentData+partialSuffix); err != nil {
+		return 0, err
+	}
+
+	var index []byte
+	for _, entry := range entries {
+		entryBytes, err := json.Marshal(entry)
+		if err != nil {
+			return 0, err
+		}
+		index = append(append(index, entryBytes...), '\n')
+	}
+	if err := os.WriteFile(name+segmentIndex+partialSuffix, index, 0664); err != nil {
+		return 0, err
+	}
+
+	return len(lines), completeSegment(name)
+}
+
+// readCompleteLines returns the lines of a gzip file that end in a newline,
+// a stream cut short by a crash ends the reading.
+func readCompleteLines(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	zip, err := gzip.NewReader(f)
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, nil // nothing was flushed
+		}
+		return nil, err
+	}
+	r := bufio.NewReader(zip)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+func readSegmentIndex(path string) ([]SegmentEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []SegmentEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry SegmentEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			break // a line cut short by a crash
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// segmentHeight returns the first height of a segment.
+func segmentHeight(path string) int {
+	height, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "blocks-"), segmentIndex))
+	return height
+}
+
+// completeSegment drops the partial suffix of a segment and its index.
+func completeSegment(name string) error {
+	if err := os.Rename(name+segmentData+partialSuffix, name+segmentData); err != nil {
+		return err
+	}
+	return os.Rename(name+segmentIndex+partialSuffix, name+segmentIndex)
+}
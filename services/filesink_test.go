@@ -0,0 +1,129 @@
+package services
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/types"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func exportBlock(height int32) *proto.Block {
+	b := util.RandomBlock()
+	b.Header.Height = height
+	b.Transactions = []*proto.Transaction{{
+		Version: 1,
+		Outputs: []*proto.TxOutput{{Amount: 10, Address: util.RandomHash()[:20], Payload: []byte(`{"metadata":"x"}`)}},
+	}}
+	return b
+}
+
+// readSegment returns the blocks of a completed segment.
+func readSegment(t *testing.T, name string) []*proto.Block {
+	f, err := os.Open(name + segmentData)
+	require.Nil(t, err)
+	defer f.Close()
+	zip, err := gzip.NewReader(f)
+	require.Nil(t, err)
+	data, err := io.ReadAll(zip)
+	require.Nil(t, err)
+
+	var blocks []*proto.Block
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		b := &proto.Block{}
+		require.Nil(t, protojson.Unmarshal([]byte(line), b))
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func TestFileSinkSegments(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFileSink(dir, 2, 1<<20)
+	require.Nil(t, err)
+
+	var blocks []*proto.Block
+	for height := int32(1); height <= 5; height++ {
+		b := exportBlock(height)
+		blocks = append(blocks, b)
+		require.Nil(t, fs.StoreBlock(b))
+	}
+	// redelivered blocks are skipped
+	require.Nil(t, fs.StoreBlock(blocks[4]))
+	require.Nil(t, fs.StoreBlock(blocks[3]))
+	require.Nil(t, fs.Close())
+
+	// two full segments and the one closed with the sink
+	names := []string{"blocks-0000000000000001", "blocks-0000000000000003", "blocks-0000000000000005"}
+	for i, name := range names {
+		name = filepath.Join(dir, name)
+		got := readSegment(t, name)
+		want := blocks[2*i : min(2*i+2, len(blocks))]
+		require.Len(t, got, len(want))
+		for j := range want {
+			assert.True(t, pb.Equal(want[j], got[j]))
+		}
+
+		entries, err := readSegmentIndex(name + segmentIndex)
+		require.Nil(t, err)
+		require.Len(t, entries, len(want))
+		for j, b := range want {
+			assert.Equal(t, SegmentEntry{Height: b.Header.Height, Hash: hex.EncodeToString(types.HashBlock(b)), Line: j, Txs: 1}, entries[j])
+		}
+	}
+
+	partials, err := filepath.Glob(filepath.Join(dir, "*"+partialSuffix))
+	require.Nil(t, err)
+	assert.Empty(t, partials)
+}
+
+func TestFileSinkRecovery(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFileSink(dir, 100, 1<<20)
+	require.Nil(t, err)
+	b1, b2 := exportBlock(1), exportBlock(2)
+	require.Nil(t, fs.StoreBlock(b1))
+	require.Nil(t, fs.StoreBlock(b2))
+
+	// the process dies with the segment open, a line written without its
+	// index entry and the last line half written
+	name := filepath.Join(dir, "blocks-0000000000000001")
+	b3 := exportBlock(3)
+	line, err := protojson.Marshal(b3)
+	require.Nil(t, err)
+	_, err = fs.seg.zip.Write(append(line, '\n'))
+	require.Nil(t, err)
+	_, err = fs.seg.zip.Write([]byte(`{"header":`))
+	require.Nil(t, err)
+	require.Nil(t, fs.seg.zip.Flush())
+	require.Nil(t, fs.seg.data.Close())
+	require.Nil(t, fs.seg.index.Close())
+
+	fs, err = NewFileSink(dir, 100, 1<<20)
+	require.Nil(t, err)
+	got := readSegment(t, name)
+	require.Len(t, got, 2)
+	assert.True(t, pb.Equal(b2, got[1]))
+	entries, err := readSegmentIndex(name + segmentIndex)
+	require.Nil(t, err)
+	assert.Len(t, entries, 2)
+
+	// the redelivered last block is skipped, the unindexed one is exported
+	// again in a new segment
+	require.Nil(t, fs.StoreBlock(b2))
+	require.Nil(t, fs.StoreBlock(b3))
+	require.Nil(t, fs.Close())
+	got = readSegment(t, filepath.Join(dir, "blocks-0000000000000003"))
+	require.Len(t, got, 1)
+	assert.True(t, pb.Equal(b3, got[0]))
+}
@@ -106,6 +106,12 @@ func VerifyRootHash(b *proto.Block) bool {
 	return bytes.Equal(b.Header.RootHash, tree.MerkleRoot())
 }
 
+// SignedHeader returns the header of a block with the proposer signature,
+// enough to verify the signature without the transactions.
+func SignedHeader(b *proto.Block) *proto.Block {
+	return &proto.Block{Header: b.Header, PublicKey: b.PublicKey, Signature: b.Signature}
+}
+
 // GetMerkleTree returns the merkle tree of a block.
 func GetMerkleTree(b *proto.Block) (*merkletree.MerkleTree, error) {
 	// block has to have transactions to create a merkle tree
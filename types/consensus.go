@@ -0,0 +1,100 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+)
+
+// HashConsensusMessage returns the hash validators sign for a consensus message.
+// The canonical encoding is the message type, a zero byte, the big endian view
+// and sequence numbers and the hash of the block the message refers to.
+func HashConsensusMessage(msgType string, view, sequence int64, blockHash []byte) []byte {
+	buf := make([]byte, 0, len(msgType)+1+16+len(blockHash))
+	buf = append(buf, msgType...)
+	buf = append(buf, 0)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(view))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(sequence))
+	buf = append(buf, blockHash...)
+
+	hash := sha3.New512()
+	hash.Write(buf)
+
+	return hash.Sum(nil)
+}
+
+// QuorumSize returns the votes needed to tolerate f = (n-1)/3 byzantine
+// validators out of n. A quorum of n-f, that is at least (n+f+1)/2, makes any
+// two quorums share an honest validator, which never votes for two conflicting
+// blocks, it is 2f+1 when n = 3f+1.
+func QuorumSize(n int) int {
+	return n - (n-1)/3
+}
+
+// VerifyCommitCertificate verifies that a commit certificate carries valid
+// commit signatures from a quorum of the given validator set.
+func VerifyCommitCertificate(cert *proto.CommitCertificate, validators []*crypto.PublicKey) bool {
+	known := make(map[string]*crypto.PublicKey, len(validators))
+	for _, v := range validators {
+		known[v.String()] = v
+	}
+	if err := verifyCommitVotes(cert, known, QuorumSize(len(known))); err != nil {
+		logger.Error().Msg(err.Error())
+		return false
+	}
+	return true
+}
+
+// VerifyBlockCertificate verifies that a commit certificate finalizes a block:
+// it names the block and carries valid commit signatures from a quorum of the
+// validator set the block was committed under.
+func VerifyBlockCertificate(cert *proto.CommitCertificate, b *proto.Block, set *proto.ValidatorSet) error {
+	if cert == nil {
+		return errors.New("no commit certificate")
+	}
+	if !bytes.Equal(cert.BlockHash, HashBlock(b)) || cert.Sequence != int64(b.GetHeader().GetHeight()) {
+		return fmt.Errorf("commit certificate is for block [%x] at height [%d]", cert.BlockHash, cert.Sequence)
+	}
+	known := make(map[string]*crypto.PublicKey, len(set.GetValidators()))
+	for _, v := range set.GetValidators() {
+		if len(v) == crypto.PubKeyLen {
+			known[hex.EncodeToString(v)] = crypto.PublicKeyFromBytes(v)
+		}
+	}
+	return verifyCommitVotes(cert, known, ValidatorSetQuorum(set))
+}
+
+// verifyCommitVotes checks the votes of a certificate against the validators by
+// hex encoded public key, a quorum of distinct validators has to sign.
+func verifyCommitVotes(cert *proto.CommitCertificate, known map[string]*crypto.PublicKey, quorum int) error {
+	if cert == nil || len(cert.BlockHash) == 0 {
+		return errors.New("empty commit certificate")
+	}
+
+	var (
+		hash    = HashConsensusMessage("Commit", cert.View, cert.Sequence, cert.BlockHash)
+		signers = make(map[string]bool)
+	)
+	for _, vote := range cert.Votes {
+		pubKey, ok := known[hex.EncodeToString(vote.PublicKey)]
+		if !ok {
+			return fmt.Errorf("commit vote from unknown validator [%s]", hex.EncodeToString(vote.PublicKey))
+		}
+		if len(vote.Signature) != crypto.SignatureLen || !crypto.SignatureFromBytes(vote.Signature).Verify(pubKey, hash) {
+			return fmt.Errorf("invalid commit vote signature from [%s]", pubKey.String())
+		}
+		signers[pubKey.String()] = true
+	}
+
+	if len(signers) < quorum {
+		return fmt.Errorf("commit certificate has [%d] votes, quorum is [%d]", len(signers), quorum)
+	}
+	return nil
+}
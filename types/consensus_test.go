@@ -0,0 +1,99 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashConsensusMessage(t *testing.T) {
+	blockHash := HashBlock(util.RandomBlock())
+	hash := HashConsensusMessage("Prepare", 1, 2, blockHash)
+	assert.Equal(t, 64, len(hash))
+	assert.Equal(t, hash, HashConsensusMessage("Prepare", 1, 2, blockHash))
+
+	// every field is part of the hash
+	assert.NotEqual(t, hash, HashConsensusMessage("Commit", 1, 2, blockHash))
+	assert.NotEqual(t, hash, HashConsensusMessage("Prepare", 2, 2, blockHash))
+	assert.NotEqual(t, hash, HashConsensusMessage("Prepare", 1, 3, blockHash))
+	assert.NotEqual(t, hash, HashConsensusMessage("Prepare", 1, 2, nil))
+}
+
+func TestQuorumSize(t *testing.T) {
+	assert.Equal(t, 1, QuorumSize(1))
+	assert.Equal(t, 2, QuorumSize(2))
+	assert.Equal(t, 3, QuorumSize(3))
+	assert.Equal(t, 3, QuorumSize(4))
+	assert.Equal(t, 4, QuorumSize(5))
+	assert.Equal(t, 5, QuorumSize(6))
+	assert.Equal(t, 5, QuorumSize(7))
+	assert.Equal(t, 7, QuorumSize(10))
+}
+
+func TestVerifyCommitCertificate(t *testing.T) {
+	var (
+		keys       = make([]*crypto.PrivateKey, 4)
+		validators = make([]*crypto.PublicKey, 4)
+		blockHash  = HashBlock(util.RandomBlock())
+		hash       = HashConsensusMessage("Commit", 0, 1, blockHash)
+	)
+	for i := range keys {
+		keys[i] = crypto.GeneratePrivateKey()
+		validators[i] = keys[i].Public()
+	}
+
+	cert := &proto.CommitCertificate{View: 0, Sequence: 1, BlockHash: blockHash}
+	for _, key := range keys[:2] {
+		cert.Votes = append(cert.Votes, &proto.CommitVote{PublicKey: key.Public().Bytes(), Signature: key.Sign(hash).Bytes()})
+	}
+	// 2 out of 4 is below the 2f+1 quorum
+	assert.False(t, VerifyCommitCertificate(cert, validators))
+
+	cert.Votes = append(cert.Votes, &proto.CommitVote{PublicKey: keys[2].Public().Bytes(), Signature: keys[2].Sign(hash).Bytes()})
+	assert.True(t, VerifyCommitCertificate(cert, validators))
+
+	// a vote from outside the validator set invalidates the certificate
+	outsider := crypto.GeneratePrivateKey()
+	forged := &proto.CommitCertificate{View: 0, Sequence: 1, BlockHash: blockHash, Votes: append(cert.Votes,
+		&proto.CommitVote{PublicKey: outsider.Public().Bytes(), Signature: outsider.Sign(hash).Bytes()})}
+	assert.False(t, VerifyCommitCertificate(forged, validators))
+
+	// votes must sign the certified block
+	cert.BlockHash = HashBlock(util.RandomBlock())
+	assert.False(t, VerifyCommitCertificate(cert, validators))
+}
+
+func TestVerifyBlockCertificate(t *testing.T) {
+	var (
+		keys  = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		set   = NewValidatorSet([]*crypto.PublicKey{keys[0].Public(), keys[1].Public(), keys[2].Public(), keys[3].Public()})
+		block = util.RandomBlock()
+	)
+	block.Header.Height = 1
+	certify := func(b *proto.Block, signers ...*crypto.PrivateKey) *proto.CommitCertificate {
+		cert := &proto.CommitCertificate{Sequence: int64(b.Header.Height), BlockHash: HashBlock(b)}
+		hash := HashConsensusMessage("Commit", 0, cert.Sequence, cert.BlockHash)
+		for _, key := range signers {
+			cert.Votes = append(cert.Votes, &proto.CommitVote{PublicKey: key.Public().Bytes(), Signature: key.Sign(hash).Bytes()})
+		}
+		return cert
+	}
+
+	assert.Nil(t, VerifyBlockCertificate(certify(block, keys[:3]...), block, set))
+	assert.Error(t, VerifyBlockCertificate(nil, block, set))
+
+	// the same vote twice does not make a quorum
+	assert.Error(t, VerifyBlockCertificate(certify(block, keys[0], keys[1], keys[1]), block, set))
+
+	// a valid certificate of another block does not finalize this one
+	other := util.RandomBlock()
+	other.Header.Height = 1
+	assert.Error(t, VerifyBlockCertificate(certify(other, keys[:3]...), block, set))
+
+	// the quorum of the set applies
+	set.Quorum = 4
+	assert.Error(t, VerifyBlockCertificate(certify(block, keys[:3]...), block, set))
+}
@@ -0,0 +1,137 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"golang.org/x/crypto/sha3"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+)
+
+// NewBlockEvidence returns the evidence of a validator signing two different
+// blocks at one height, or nil when the blocks do not prove it.
+func NewBlockEvidence(first, second *proto.Block) *proto.Evidence {
+	ev := &proto.Evidence{
+		PublicKey:   first.GetPublicKey(),
+		FirstBlock:  SignedHeader(first),
+		SecondBlock: SignedHeader(second),
+	}
+	if !VerifyEvidence(ev) {
+		return nil
+	}
+	return ev
+}
+
+// NewVoteEvidence returns the evidence of a validator voting for two different
+// blocks in one view and sequence, or nil when the votes do not prove it.
+func NewVoteEvidence(pubKey *crypto.PublicKey, first, second *proto.ConsensusMessage) *proto.Evidence {
+	strip := func(vote *proto.ConsensusMessage) *proto.ConsensusMessage {
+		stripped := pb.Clone(vote).(*proto.ConsensusMessage)
+		stripped.Proof = nil
+		if stripped.Block != nil {
+			stripped.Block = SignedHeader(stripped.Block)
+		}
+		return stripped
+	}
+	ev := &proto.Evidence{
+		PublicKey:  pubKey.Bytes(),
+		FirstVote:  strip(first),
+		SecondVote: strip(second),
+	}
+	if !VerifyEvidence(ev) {
+		return nil
+	}
+	return ev
+}
+
+// VerifyEvidence checks that evidence proves equivocation on its own, both
+// conflicting items carry valid signatures of the accused validator.
+func VerifyEvidence(ev *proto.Evidence) bool {
+	if ev == nil || len(ev.PublicKey) != crypto.PubKeyLen {
+		return false
+	}
+	pubKey := crypto.PublicKeyFromBytes(ev.PublicKey)
+
+	switch {
+	case ev.FirstBlock != nil && ev.SecondBlock != nil:
+		return verifyConflictingBlocks(pubKey, ev.FirstBlock, ev.SecondBlock)
+	case ev.FirstVote != nil && ev.SecondVote != nil:
+		return verifyConflictingVotes(pubKey, ev.FirstVote, ev.SecondVote)
+	}
+	return false
+}
+
+func verifyConflictingBlocks(pubKey *crypto.PublicKey, first, second *proto.Block) bool {
+	for _, b := range []*proto.Block{first, second} {
+		if b.Header == nil || !bytes.Equal(b.PublicKey, pubKey.Bytes()) || len(b.Signature) != crypto.SignatureLen {
+			return false
+		}
+		if !crypto.SignatureFromBytes(b.Signature).Verify(pubKey, HashBlock(b)) {
+			return false
+		}
+	}
+	return first.Header.Height == second.Header.Height && !bytes.Equal(HashBlock(first), HashBlock(second))
+}
+
+func verifyConflictingVotes(pubKey *crypto.PublicKey, first, second *proto.ConsensusMessage) bool {
+	votes := []*proto.ConsensusMessage{first, second}
+	for _, vote := range votes {
+		if vote.Block == nil || vote.Block.Header == nil || vote.NodeID != pubKey.String() || len(vote.Signature) != crypto.SignatureLen {
+			return false
+		}
+		hash := HashConsensusMessage(vote.Type, vote.View, vote.Sequence, HashBlock(vote.Block))
+		if !crypto.SignatureFromBytes(vote.Signature).Verify(pubKey, hash) {
+			return false
+		}
+	}
+	return first.Type == second.Type && first.View == second.View && first.Sequence == second.Sequence &&
+		!bytes.Equal(HashBlock(first.Block), HashBlock(second.Block))
+}
+
+// HashEvidence returns the hash identifying a piece of evidence.
+func HashEvidence(ev *proto.Evidence) []byte {
+	b, err := pb.MarshalOptions{Deterministic: true}.Marshal(ev)
+	if err != nil {
+		logger.Error().Msgf("error marshalling evidence: %v", err)
+		panic(err)
+	}
+
+	hash := sha3.New512()
+	hash.Write(b)
+
+	return hash.Sum(nil)
+}
+
+// HashEvidenceList returns the hash a block header commits its evidence with,
+// nil for a block without evidence.
+func HashEvidenceList(list []*proto.Evidence) []byte {
+	if len(list) == 0 {
+		return nil
+	}
+
+	hash := sha3.New512()
+	for _, ev := range list {
+		hash.Write(HashEvidence(ev))
+	}
+
+	return hash.Sum(nil)
+}
+
+// VerifyBlockEvidence checks the evidence included in a block and that the
+// signed header commits to it.
+func VerifyBlockEvidence(b *proto.Block) bool {
+	if !bytes.Equal(b.GetHeader().GetEvidenceHash(), HashEvidenceList(b.Evidence)) {
+		logger.Error().Msg("evidence does not match the block header")
+		return false
+	}
+	for _, ev := range b.Evidence {
+		if !VerifyEvidence(ev) {
+			logger.Error().Msgf("invalid evidence against [%s]", hex.EncodeToString(ev.GetPublicKey()))
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedBlockAt(privKey *crypto.PrivateKey, height int32) *proto.Block {
+	b := util.RandomBlock()
+	b.Header.Height = height
+	SignBlock(privKey, b)
+	return b
+}
+
+func signedVote(privKey *crypto.PrivateKey, b *proto.Block) *proto.ConsensusMessage {
+	vote := &proto.ConsensusMessage{Type: "Prepare", Block: b, View: 0, Sequence: int64(b.Header.Height), NodeID: privKey.Public().String()}
+	vote.Signature = privKey.Sign(HashConsensusMessage(vote.Type, vote.View, vote.Sequence, HashBlock(b))).Bytes()
+	return vote
+}
+
+func TestBlockEvidence(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		first   = signedBlockAt(privKey, 3)
+		second  = signedBlockAt(privKey, 3)
+	)
+	ev := NewBlockEvidence(first, second)
+	require.NotNil(t, ev)
+	assert.True(t, VerifyEvidence(ev))
+	assert.Equal(t, privKey.Public().Bytes(), ev.PublicKey)
+
+	// the same block twice, blocks at different heights and blocks of different signers prove nothing
+	assert.Nil(t, NewBlockEvidence(first, first))
+	assert.Nil(t, NewBlockEvidence(first, signedBlockAt(privKey, 4)))
+	assert.Nil(t, NewBlockEvidence(first, signedBlockAt(crypto.GeneratePrivateKey(), 3)))
+
+	// tampering with a header breaks its signature
+	ev.SecondBlock.Header.Timestamp++
+	assert.False(t, VerifyEvidence(ev))
+}
+
+func TestVoteEvidence(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		first   = signedVote(privKey, signedBlockAt(privKey, 1))
+		second  = signedVote(privKey, signedBlockAt(privKey, 1))
+	)
+	ev := NewVoteEvidence(privKey.Public(), first, second)
+	require.NotNil(t, ev)
+	assert.True(t, VerifyEvidence(ev))
+
+	// votes in different slots do not conflict
+	other := signedVote(privKey, signedBlockAt(privKey, 2))
+	assert.Nil(t, NewVoteEvidence(privKey.Public(), first, other))
+
+	// the votes have to be signed by the accused validator
+	assert.Nil(t, NewVoteEvidence(crypto.GeneratePrivateKey().Public(), first, second))
+}
+
+func TestVerifyBlockEvidence(t *testing.T) {
+	var (
+		accused = crypto.GeneratePrivateKey()
+		privKey = crypto.GeneratePrivateKey()
+		ev      = NewBlockEvidence(signedBlockAt(accused, 1), signedBlockAt(accused, 1))
+		block   = util.RandomBlock()
+	)
+	require.NotNil(t, ev)
+	SignBlock(privKey, block)
+	assert.True(t, VerifyBlockEvidence(block))
+
+	block.Evidence = []*proto.Evidence{ev}
+	assert.False(t, VerifyBlockEvidence(block), "the header does not commit to the evidence")
+
+	block.Header.EvidenceHash = HashEvidenceList(block.Evidence)
+	SignBlock(privKey, block)
+	assert.True(t, VerifyBlockEvidence(block))
+	assert.True(t, VerifyBlock(block))
+
+	// evidence that does not prove anything invalidates the block
+	ev.SecondBlock = ev.FirstBlock
+	block.Header.EvidenceHash = HashEvidenceList(block.Evidence)
+	assert.False(t, VerifyBlockEvidence(block))
+}
@@ -0,0 +1,209 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+)
+
+// Governance actions.
+const (
+	GovernanceAddValidator    = "AddValidator"
+	GovernanceRemoveValidator = "RemoveValidator"
+	GovernanceChangeQuorum    = "ChangeQuorum"
+)
+
+// HashGovernance returns the hash validators sign to approve a change, the
+// approvals themselves are not part of it.
+func HashGovernance(g *proto.Governance) []byte {
+	unsigned := pb.Clone(g).(*proto.Governance)
+	unsigned.Approvals = nil
+	b, err := pb.MarshalOptions{Deterministic: true}.Marshal(unsigned)
+	if err != nil {
+		logger.Error().Msgf("error marshalling governance: %v", err)
+		panic(err)
+	}
+
+	hash := sha3.New512()
+	hash.Write(b)
+
+	return hash.Sum(nil)
+}
+
+// ApproveGovernance adds the approval of a validator to a change.
+func ApproveGovernance(privKey *crypto.PrivateKey, g *proto.Governance) {
+	g.Approvals = append(g.Approvals, &proto.GovernanceApproval{
+		PublicKey: privKey.Public().Bytes(),
+		Signature: privKey.Sign(HashGovernance(g)).Bytes(),
+	})
+}
+
+// NewValidatorSet returns the validator set of a chain without governance history.
+func NewValidatorSet(validators []*crypto.PublicKey) *proto.ValidatorSet {
+	set := &proto.ValidatorSet{}
+	for _, v := range validators {
+		if !hasValidator(set, v.Bytes()) {
+			set.Validators = append(set.Validators, v.Bytes())
+		}
+	}
+	return set
+}
+
+// ValidatorSetKeys returns the public keys of a validator set in order.
+func ValidatorSetKeys(set *proto.ValidatorSet) []*crypto.PublicKey {
+	keys := make([]*crypto.PublicKey, len(set.Validators))
+	for i, v := range set.Validators {
+		keys[i] = crypto.PublicKeyFromBytes(v)
+	}
+	return keys
+}
+
+// ValidatorSetQuorum returns the number of votes, or approvals, a validator set requires.
+func ValidatorSetQuorum(set *proto.ValidatorSet) int {
+	if set.Quorum > 0 {
+		return int(set.Quorum)
+	}
+	return QuorumSize(len(set.Validators))
+}
+
+func hasValidator(set *proto.ValidatorSet, pubKey []byte) bool {
+	for _, v := range set.Validators {
+		if bytes.Equal(v, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyGovernance checks a change included in the block at height against
+// the validator set the block is validated with.
+func VerifyGovernance(set *proto.ValidatorSet, g *proto.Governance, height int64) error {
+	if g.EffectiveHeight <= height {
+		return fmt.Errorf("governance effective height [%d] is not after block height [%d]", g.EffectiveHeight, height)
+	}
+
+	switch g.Action {
+	case GovernanceAddValidator:
+		if len(g.Validator) != crypto.PubKeyLen {
+			return fmt.Errorf("invalid validator public key")
+		}
+		if hasValidator(set, g.Validator) {
+			return fmt.Errorf("validator [%s] is already in the set", hex.EncodeToString(g.Validator)[:8])
+		}
+	case GovernanceRemoveValidator:
+		if !hasValidator(set, g.Validator) {
+			return fmt.Errorf("validator is not in the set")
+		}
+		if len(set.Validators) == 1 {
+			return fmt.Errorf("cannot remove the last validator")
+		}
+	case GovernanceChangeQuorum:
+		if g.Quorum != 0 && !validQuorum(int(g.Quorum), len(set.Validators)) {
+			return fmt.Errorf("invalid quorum [%d] for [%d] validators", g.Quorum, len(set.Validators))
+		}
+	default:
+		return fmt.Errorf("unknown governance action [%s]", g.Action)
+	}
+
+	var (
+		hash     = HashGovernance(g)
+		approved = make(map[string]bool)
+	)
+	for _, approval := range g.Approvals {
+		if !hasValidator(set, approval.PublicKey) || len(approval.Signature) != crypto.SignatureLen {
+			continue
+		}
+		if crypto.SignatureFromBytes(approval.Signature).Verify(crypto.PublicKeyFromBytes(approval.PublicKey), hash) {
+			approved[hex.EncodeToString(approval.PublicKey)] = true
+		}
+	}
+	if len(approved) < ValidatorSetQuorum(set) {
+		return fmt.Errorf("governance [%s] approved by [%d] validators, [%d] required", g.Action, len(approved), ValidatorSetQuorum(set))
+	}
+	return nil
+}
+
+// VerifyBlockGovernance checks the governance transactions of a block.
+func VerifyBlockGovernance(set *proto.ValidatorSet, b *proto.Block) error {
+	for _, tx := range b.Transactions {
+		if tx.Governance == nil {
+			continue
+		}
+		if err := VerifyGovernance(set, tx.Governance, int64(b.Header.Height)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NextValidatorSet returns the validator set after a committed block. The block
+// removes the validators convicted by its evidence right away, queues its
+// governance changes and applies the queued changes due at the next height.
+func NextValidatorSet(set *proto.ValidatorSet, b *proto.Block) *proto.ValidatorSet {
+	next := pb.Clone(set).(*proto.ValidatorSet)
+	next.Height = int64(b.Header.Height)
+
+	for _, ev := range b.Evidence {
+		removeFromSet(next, ev.PublicKey)
+	}
+	for _, tx := range b.Transactions {
+		if tx.Governance != nil && VerifyGovernance(set, tx.Governance, next.Height) == nil {
+			g := pb.Clone(tx.Governance).(*proto.Governance)
+			g.Approvals = nil
+			next.Pending = append(next.Pending, g)
+		}
+	}
+
+	pending := next.Pending[:0]
+	for _, g := range next.Pending {
+		if g.EffectiveHeight > next.Height+1 {
+			pending = append(pending, g)
+			continue
+		}
+		switch g.Action {
+		case GovernanceAddValidator:
+			if !hasValidator(next, g.Validator) {
+				next.Validators = append(next.Validators, g.Validator)
+			}
+		case GovernanceRemoveValidator:
+			removeFromSet(next, g.Validator)
+		case GovernanceChangeQuorum:
+			next.Quorum = g.Quorum
+		}
+	}
+	next.Pending = pending
+
+	// a quorum the changed set cannot reach, or one too small for it, falls
+	// back to the BFT quorum
+	if next.Quorum != 0 && !validQuorum(int(next.Quorum), len(next.Validators)) {
+		next.Quorum = 0
+	}
+	return next
+}
+
+// validQuorum reports whether n validators may use a quorum. Quorums smaller
+// than QuorumSize do not always share an honest validator, so two of them could
+// commit conflicting blocks.
+func validQuorum(quorum, n int) bool {
+	return quorum >= QuorumSize(n) && quorum <= n
+}
+
+// removeFromSet removes a validator, the last one always stays.
+func removeFromSet(set *proto.ValidatorSet, pubKey []byte) {
+	if len(set.Validators) == 1 {
+		return
+	}
+	validators := set.Validators[:0]
+	for _, v := range set.Validators {
+		if !bytes.Equal(v, pubKey) {
+			validators = append(validators, v)
+		}
+	}
+	set.Validators = validators
+}
@@ -0,0 +1,110 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/janrockdev/darkblock/crypto"
+	"github.com/janrockdev/darkblock/proto"
+	"github.com/janrockdev/darkblock/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func governanceBlock(height int32, g *proto.Governance) *proto.Block {
+	b := util.RandomBlock()
+	b.Header.Height = height
+	if g != nil {
+		b.Transactions = []*proto.Transaction{{Version: 1, Governance: g}}
+	}
+	return b
+}
+
+func TestVerifyGovernance(t *testing.T) {
+	var (
+		keys = []*crypto.PrivateKey{crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()}
+		set  = NewValidatorSet([]*crypto.PublicKey{keys[0].Public(), keys[1].Public(), keys[2].Public(), keys[3].Public()})
+		g    = &proto.Governance{Action: GovernanceAddValidator, Validator: crypto.GeneratePrivateKey().Public().Bytes(), EffectiveHeight: 5}
+	)
+
+	// three of four validators have to approve
+	ApproveGovernance(keys[0], g)
+	ApproveGovernance(keys[1], g)
+	assert.Error(t, VerifyGovernance(set, g, 1))
+	ApproveGovernance(keys[1], g) // approving twice does not count twice
+	assert.Error(t, VerifyGovernance(set, g, 1))
+	ApproveGovernance(crypto.GeneratePrivateKey(), g) // outsiders do not count
+	assert.Error(t, VerifyGovernance(set, g, 1))
+	ApproveGovernance(keys[2], g)
+	assert.Nil(t, VerifyGovernance(set, g, 1))
+
+	// the change has to take effect after the block carrying it
+	assert.Error(t, VerifyGovernance(set, g, 5))
+
+	// approvals do not carry over to a modified change
+	g.EffectiveHeight = 6
+	assert.Error(t, VerifyGovernance(set, g, 1))
+}
+
+func TestVerifyGovernanceActions(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		set     = NewValidatorSet([]*crypto.PublicKey{privKey.Public(), crypto.GeneratePrivateKey().Public(), crypto.GeneratePrivateKey().Public()})
+	)
+	set.Quorum = 1 // approved by the signing validator alone
+	approved := func(g *proto.Governance) *proto.Governance {
+		g.EffectiveHeight = 2
+		ApproveGovernance(privKey, g)
+		return g
+	}
+
+	assert.Error(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceAddValidator, Validator: privKey.Public().Bytes()}), 1))
+	assert.Error(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceRemoveValidator, Validator: crypto.GeneratePrivateKey().Public().Bytes()}), 1))
+	assert.Nil(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceRemoveValidator, Validator: privKey.Public().Bytes()}), 1))
+	assert.Nil(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceChangeQuorum, Quorum: 3}), 1))
+	assert.Nil(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceChangeQuorum, Quorum: 0}), 1))
+	// two quorums of two out of three share no honest validator when one is faulty
+	assert.Error(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceChangeQuorum, Quorum: 2}), 1))
+	assert.Error(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceChangeQuorum, Quorum: 1}), 1))
+	assert.Error(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceChangeQuorum, Quorum: 4}), 1))
+
+	// a majority of seven is not enough, two faulty validators could commit conflicting blocks
+	seven := []*crypto.PublicKey{privKey.Public()}
+	for len(seven) < 7 {
+		seven = append(seven, crypto.GeneratePrivateKey().Public())
+	}
+	set = NewValidatorSet(seven)
+	set.Quorum = 1
+	assert.Error(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceChangeQuorum, Quorum: 4}), 1))
+	assert.Nil(t, VerifyGovernance(set, approved(&proto.Governance{Action: GovernanceChangeQuorum, Quorum: 5}), 1))
+	assert.Error(t, VerifyGovernance(set, approved(&proto.Governance{Action: "Unknown"}), 1))
+}
+
+func TestNextValidatorSet(t *testing.T) {
+	var (
+		privKey = crypto.GeneratePrivateKey()
+		added   = crypto.GeneratePrivateKey().Public()
+		set     = NewValidatorSet([]*crypto.PublicKey{privKey.Public()})
+		g       = &proto.Governance{Action: GovernanceAddValidator, Validator: added.Bytes(), EffectiveHeight: 4}
+	)
+	ApproveGovernance(privKey, g)
+
+	// the change waits for its height
+	set = NextValidatorSet(set, governanceBlock(1, g))
+	require.Len(t, set.Pending, 1)
+	assert.Len(t, set.Validators, 1)
+	assert.Nil(t, set.Pending[0].Approvals)
+	set = NextValidatorSet(set, governanceBlock(2, nil))
+	assert.Len(t, set.Validators, 1)
+
+	// the block before the effective height is the last one of the old set
+	set = NextValidatorSet(set, governanceBlock(3, nil))
+	assert.Empty(t, set.Pending)
+	assert.Equal(t, []*crypto.PublicKey{privKey.Public(), added}, ValidatorSetKeys(set))
+	assert.Equal(t, int64(3), set.Height)
+	assert.Equal(t, 2, ValidatorSetQuorum(set))
+
+	// unapproved changes are ignored
+	removal := &proto.Governance{Action: GovernanceRemoveValidator, Validator: privKey.Public().Bytes(), EffectiveHeight: 5}
+	set = NextValidatorSet(set, governanceBlock(4, removal))
+	assert.Empty(t, set.Pending)
+}